@@ -7,6 +7,7 @@ import (
 	"log"
 
 	"github.com/pivotalservices/file-downloader-resource/config"
+	"github.com/pivotalservices/file-downloader-resource/file"
 	"github.com/pivotalservices/file-downloader-resource/types"
 )
 
@@ -25,11 +26,68 @@ func main() {
 		fatal("constructing driver", err)
 	}
 
-	version, err := provider.LatestVersion()
+	versions, err := provider.VersionsSince(request.Version)
 	if err != nil {
-		fatal("fetching version", err)
+		fatal("fetching versions", err)
 	}
-	json.NewEncoder(os.Stdout).Encode(types.CheckResponse{*version})
+
+	if len(request.Source.Product) > 0 {
+		versions, err = resolveVersions(provider, file.FromSource, request.Source, request.Version, versions)
+		if err != nil {
+			fatal("resolving versions", err)
+		}
+	}
+	json.NewEncoder(os.Stdout).Encode(types.CheckResponse(versions))
+}
+
+// resolveVersions - pins the version ranges of the product of source at each of versions to the versions they
+// resolve to now, keeping those of previous, and adds a version when a range of the product at previous
+// resolves to a newer version than it did. Versions older than the newest are left unpinned when they can't be
+// resolved, so one bad product file doesn't stop check, and so are products whose variables are only set by
+// the vars of get, as their ranges can only be resolved by in
+func resolveVersions(configProvider config.Provider, newFileProvider func(types.Source) (file.Provider, error), source types.Source, previous types.Version, versions []types.Version) ([]types.Version, error) {
+	var result []types.Version
+	for i, version := range versions {
+		newest := i == len(versions)-1
+		if i == 0 && version.Ref == previous.Ref {
+			result = append(result, previous)
+			if !newest {
+				continue
+			}
+		}
+
+		resolved, err := resolve(configProvider, newFileProvider, source, version.Ref)
+		if err != nil {
+			if productFileError, ok := err.(*config.ProductFileError); newest && !(ok && productFileError.OnlyUnresolvedVariables()) {
+				return nil, err
+			}
+			resolved = ""
+		}
+		if i == 0 && version.Ref == previous.Ref && resolved == previous.Resolved {
+			continue
+		}
+		result = append(result, types.Version{Ref: version.Ref, Resolved: resolved})
+	}
+
+	return result, nil
+}
+
+// resolve - returns the versions that the version ranges of the product of source at ref resolve to
+func resolve(configProvider config.Provider, newFileProvider func(types.Source) (file.Provider, error), source types.Source, ref string) (string, error) {
+	versionInfo, err := configProvider.GetVersionInfo(ref, source.Product)
+	if err != nil {
+		return "", err
+	}
+	resolver, err := file.NewResolver("")
+	if err != nil {
+		return "", err
+	}
+	err = resolver.ResolveProduct(file.NewProviders(source, newFileProvider), versionInfo)
+	if err != nil {
+		return "", err
+	}
+
+	return resolver.Resolved(), nil
 }
 
 func fatal(doing string, err error) {
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/file-downloader-resource/config"
+	configfakes "github.com/pivotalservices/file-downloader-resource/config/fakes"
+	"github.com/pivotalservices/file-downloader-resource/file"
+	filefakes "github.com/pivotalservices/file-downloader-resource/file/fakes"
+	"github.com/pivotalservices/file-downloader-resource/types"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestCheck(t *testing.T) {
+	spec.Run(t, "check", testCheck, spec.Report(report.Terminal{}))
+}
+
+func testCheck(t *testing.T, when spec.G, it spec.S) {
+	var (
+		configProvider  *configfakes.FakeProvider
+		fileProvider    *filefakes.FakeProvider
+		newFileProvider func(types.Source) (file.Provider, error)
+		source          types.Source
+		version         string
+	)
+	it.Before(func() {
+		RegisterTestingT(t)
+		version = "~2.1"
+		configProvider = &configfakes.FakeProvider{}
+		configProvider.GetVersionInfoStub = func(revision, productName string) (*types.VersionInfo, error) {
+			return &types.VersionInfo{Version: version, PivotalProduct: "elastic-runtime", FilePattern: "cf-*.pivotal"}, nil
+		}
+		fileProvider = &filefakes.FakeProvider{}
+		fileProvider.VersionsReturns([]string{"2.1.5", "2.1.6"}, nil)
+		newFileProvider = func(types.Source) (file.Provider, error) {
+			return fileProvider, nil
+		}
+		source = types.Source{Product: "pas"}
+	})
+
+	when("resolving the versions of the product", func() {
+		it("pins each new version to the versions its ranges resolve to", func() {
+			versions, err := resolveVersions(configProvider, newFileProvider, source, types.Version{}, []types.Version{{Ref: "abc123"}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versions).Should(Equal([]types.Version{{Ref: "abc123", Resolved: "elastic-runtime+cf-%2A.pivotal+~2.1=2.1.6"}}))
+
+			revision, productName := configProvider.GetVersionInfoArgsForCall(0)
+			Expect(revision).Should(Equal("abc123"))
+			Expect(productName).Should(Equal("pas"))
+		})
+
+		it("keeps the previous version as it was and resolves the newer ones", func() {
+			previous := types.Version{Ref: "abc123", Resolved: "elastic-runtime+cf-%2A.pivotal+~2.1=2.1.5"}
+			versions, err := resolveVersions(configProvider, newFileProvider, source, previous, []types.Version{{Ref: "abc123"}, {Ref: "def456"}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versions).Should(Equal([]types.Version{previous, {Ref: "def456", Resolved: "elastic-runtime+cf-%2A.pivotal+~2.1=2.1.6"}}))
+			Expect(configProvider.GetVersionInfoCallCount()).Should(Equal(1))
+		})
+
+		it("returns only the previous version while its ranges resolve to the same versions", func() {
+			previous := types.Version{Ref: "abc123", Resolved: "elastic-runtime+cf-%2A.pivotal+~2.1=2.1.6"}
+			versions, err := resolveVersions(configProvider, newFileProvider, source, previous, []types.Version{{Ref: "abc123"}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versions).Should(Equal([]types.Version{previous}))
+		})
+
+		it("adds a version when a range of the previous version resolves to a newer version", func() {
+			previous := types.Version{Ref: "abc123", Resolved: "elastic-runtime+cf-%2A.pivotal+~2.1=2.1.5"}
+			versions, err := resolveVersions(configProvider, newFileProvider, source, previous, []types.Version{{Ref: "abc123"}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versions).Should(Equal([]types.Version{previous, {Ref: "abc123", Resolved: "elastic-runtime+cf-%2A.pivotal+~2.1=2.1.6"}}))
+		})
+
+		it("leaves versions without ranges unpinned", func() {
+			version = "2.1.5"
+			versions, err := resolveVersions(configProvider, newFileProvider, source, types.Version{Ref: "abc123"}, []types.Version{{Ref: "abc123"}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versions).Should(Equal([]types.Version{{Ref: "abc123"}}))
+			Expect(fileProvider.VersionsCallCount()).Should(Equal(0))
+		})
+
+		it("leaves older versions that can't be resolved unpinned", func() {
+			configProvider.GetVersionInfoStub = func(revision, productName string) (*types.VersionInfo, error) {
+				if revision == "def456" {
+					return nil, &config.ProductFileError{File: "pas.yml", Problems: []config.Problem{{Line: 1, Message: "version is required"}}}
+				}
+				return &types.VersionInfo{Version: version, PivotalProduct: "elastic-runtime", FilePattern: "cf-*.pivotal"}, nil
+			}
+			versions, err := resolveVersions(configProvider, newFileProvider, source, types.Version{}, []types.Version{{Ref: "abc123"}, {Ref: "def456"}, {Ref: "fed789"}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versions).Should(Equal([]types.Version{
+				{Ref: "abc123", Resolved: "elastic-runtime+cf-%2A.pivotal+~2.1=2.1.6"},
+				{Ref: "def456"},
+				{Ref: "fed789", Resolved: "elastic-runtime+cf-%2A.pivotal+~2.1=2.1.6"},
+			}))
+		})
+
+		it("returns an error when the newest version can't be resolved", func() {
+			configProvider.GetVersionInfoStub = func(revision, productName string) (*types.VersionInfo, error) {
+				if revision == "def456" {
+					return nil, &config.ProductFileError{File: "pas.yml", Problems: []config.Problem{{Line: 1, Message: "version is required"}}}
+				}
+				return &types.VersionInfo{Version: version, PivotalProduct: "elastic-runtime", FilePattern: "cf-*.pivotal"}, nil
+			}
+			_, err := resolveVersions(configProvider, newFileProvider, source, types.Version{}, []types.Version{{Ref: "abc123"}, {Ref: "def456"}})
+			Expect(err).Should(MatchError(ContainSubstring("version is required")))
+		})
+
+		it("leaves products whose variables are only set by the vars of get unpinned", func() {
+			configProvider.GetVersionInfoReturns(nil, &config.ProductFileError{File: "pas.yml", Problems: []config.Problem{{Line: 1, Message: "unresolved variable ((pas_version))"}}})
+			versions, err := resolveVersions(configProvider, newFileProvider, source, types.Version{Ref: "abc123"}, []types.Version{{Ref: "abc123"}, {Ref: "def456"}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versions).Should(Equal([]types.Version{{Ref: "abc123"}, {Ref: "def456"}}))
+			Expect(fileProvider.VersionsCallCount()).Should(Equal(0))
+		})
+
+		it("returns an error when a range can't be resolved", func() {
+			version = "~2.2"
+			_, err := resolveVersions(configProvider, newFileProvider, source, types.Version{}, []types.Version{{Ref: "abc123"}})
+			Expect(err).Should(MatchError("no version of product elastic-runtime satisfies ~2.2"))
+		})
+	})
+}
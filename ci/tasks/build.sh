@@ -30,6 +30,8 @@ dep ensure
 CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -o ${OUTPUT_DIR}/check-linux -ldflags "-X main.VERSION=${DRAFT_VERSION}" check/main.go
 CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -o ${OUTPUT_DIR}/in-linux -ldflags "-X main.VERSION=${DRAFT_VERSION}" in/main.go
 CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -o ${OUTPUT_DIR}/out-linux out/main.go
+CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -o ${OUTPUT_DIR}/validate-linux -ldflags "-X main.VERSION=${DRAFT_VERSION}" validate/main.go
+CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -o ${OUTPUT_DIR}/lint-linux -ldflags "-X main.VERSION=${DRAFT_VERSION}" lint/main.go
 
 echo ${DRAFT_VERSION} > ${OUTPUT_DIR}/name
 echo ${DRAFT_VERSION} > ${OUTPUT_DIR}/tag
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pivotalservices/file-downloader-resource/types"
+)
+
+// DirProvider - reads product configuration from a local directory
+type DirProvider struct {
+	VersionRoot  string
+	OverlayRoots []string
+	Product      string
+	Vars         map[string]string
+}
+
+// GetVersionInfo - returns the version info for a product if the directory is still at revision
+func (provider *DirProvider) GetVersionInfo(revision, productName string) (*types.VersionInfo, error) {
+	current, err := provider.LatestVersion()
+	if err != nil {
+		return nil, err
+	}
+	if len(revision) > 0 && revision != current.Ref {
+		return nil, fmt.Errorf("revision %s is no longer available in %s, current revision is %s", revision, provider.VersionRoot, current.Ref)
+	}
+
+	return provider.files().load(productName)
+}
+
+// ListProducts - returns the products with a file in the directory, if it is still at revision
+func (provider *DirProvider) ListProducts(revision string) ([]string, error) {
+	current, err := provider.LatestVersion()
+	if err != nil {
+		return nil, err
+	}
+	if len(revision) > 0 && revision != current.Ref {
+		return nil, fmt.Errorf("revision %s is no longer available in %s, current revision is %s", revision, provider.VersionRoot, current.Ref)
+	}
+
+	files, err := provider.productFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	return productNamesOf(files, versionRoots(provider.VersionRoot, provider.OverlayRoots)), nil
+}
+
+// LatestVersion - returns a digest of the product files in the directory
+func (provider *DirProvider) LatestVersion() (*types.Version, error) {
+	files, err := provider.productFiles()
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no product files found in %s", provider.VersionRoot)
+	}
+	sort.Strings(files)
+
+	hash := sha256.New()
+	for _, file := range files {
+		bytes, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		name, err := filepath.Rel(provider.VersionRoot, file)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(hash, "%s\x00%d\x00", name, len(bytes))
+		hash.Write(bytes)
+	}
+
+	return &types.Version{Ref: fmt.Sprintf("%x", hash.Sum(nil))}, nil
+}
+
+// VersionsSince - returns the latest version only, as earlier directory contents are not retained
+func (provider *DirProvider) VersionsSince(previous types.Version) ([]types.Version, error) {
+	return latestVersionOnly(provider)
+}
+
+func (provider *DirProvider) files() *productFiles {
+	return &productFiles{
+		roots:  versionRoots(provider.VersionRoot, provider.OverlayRoots),
+		vars:   provider.Vars,
+		exists: fileExists,
+		read:   ioutil.ReadFile,
+	}
+}
+
+// productFiles - returns the product files in each root, or only the files product is merged from when it is set
+func (provider *DirProvider) productFiles() ([]string, error) {
+	var chain []string
+	if len(provider.Product) > 0 {
+		chain = provider.files().chain(provider.Product)
+	}
+
+	var files []string
+	for _, root := range versionRoots(provider.VersionRoot, provider.OverlayRoots) {
+		matches, err := filepath.Glob(filepath.Join(root, "*"))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, match := range matches {
+			if isProductFileName(match) && (len(chain) == 0 || containsString(chain, productNameOf(match))) {
+				files = append(files, match)
+			}
+		}
+	}
+
+	return files, nil
+}
+
+func fileExists(name string) (bool, error) {
+	info, err := os.Stat(name)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return !info.IsDir(), nil
+}
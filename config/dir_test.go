@@ -0,0 +1,347 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/file-downloader-resource/config"
+	"github.com/pivotalservices/file-downloader-resource/types"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestDirProvider(t *testing.T) {
+	spec.Run(t, "DirProvider", testDirProvider, spec.Report(report.Terminal{}))
+}
+
+func testDirProvider(t *testing.T, when spec.G, it spec.S) {
+	var (
+		versionRoot string
+		provider    *config.DirProvider
+	)
+	it.Before(func() {
+		RegisterTestingT(t)
+		var err error
+		versionRoot, err = ioutil.TempDir("", "dir-provider")
+		Expect(err).ShouldNot(HaveOccurred())
+		err = ioutil.WriteFile(filepath.Join(versionRoot, "pas.yml"), []byte("version: 2.1.5\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\n"), 0644)
+		Expect(err).ShouldNot(HaveOccurred())
+		provider = &config.DirProvider{
+			VersionRoot: versionRoot,
+		}
+	})
+	it.After(func() {
+		os.RemoveAll(versionRoot)
+	})
+	when("directory is unchanged", func() {
+		it("returns the same version", func() {
+			originalVersion, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(originalVersion.Ref).ShouldNot(BeEmpty())
+
+			newVersion, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(newVersion.Ref).Should(Equal(originalVersion.Ref))
+		})
+	})
+
+	when("a product file changes", func() {
+		it("returns a new version", func() {
+			originalVersion, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			err = ioutil.WriteFile(filepath.Join(versionRoot, "opsman.yml"), []byte("version: 2.1.3\n"), 0644)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			newVersion, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(newVersion.Ref).ShouldNot(Equal(originalVersion.Ref))
+		})
+	})
+
+	when("watching a single product", func() {
+		it.Before(func() {
+			provider.Product = "pas"
+		})
+		it("returns the same version when another product changes", func() {
+			originalVersion, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			err = ioutil.WriteFile(filepath.Join(versionRoot, "opsman.yml"), []byte("version: 2.1.3\n"), 0644)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			newVersion, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(newVersion.Ref).Should(Equal(originalVersion.Ref))
+		})
+
+		it("returns a new version when the product changes", func() {
+			originalVersion, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			err = ioutil.WriteFile(filepath.Join(versionRoot, "pas.yml"), []byte("version: 2.1.6\n"), 0644)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			newVersion, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(newVersion.Ref).ShouldNot(Equal(originalVersion.Ref))
+		})
+	})
+
+	when("watching a single product that extends another", func() {
+		it.Before(func() {
+			provider.Product = "pas"
+			Expect(ioutil.WriteFile(filepath.Join(versionRoot, "pas.yml"), []byte("extends: pas-base\nversion: 2.1.6\n"), 0644)).Should(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(versionRoot, "pas-base.yml"), []byte("product: elastic-runtime\nfile_pattern: cf-*.pivotal\n"), 0644)).Should(Succeed())
+		})
+
+		it("returns a new version when only the file it extends changes", func() {
+			originalVersion, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			err = ioutil.WriteFile(filepath.Join(versionRoot, "pas-base.yml"), []byte("product: elastic-runtime\nfile_pattern: srt-*.pivotal\n"), 0644)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			newVersion, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(newVersion.Ref).ShouldNot(Equal(originalVersion.Ref))
+		})
+	})
+
+	when("directory has no product files", func() {
+		it("returns an error", func() {
+			os.Remove(filepath.Join(versionRoot, "pas.yml"))
+			_, err := provider.LatestVersion()
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	when("getting version info", func() {
+		it("returns the product configuration", func() {
+			version, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			versionInfo, err := provider.GetVersionInfo(version.Ref, "pas")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.Version).Should(Equal("2.1.5"))
+			Expect(versionInfo.PivotalProduct).Should(Equal("elastic-runtime"))
+			Expect(versionInfo.FilePattern).Should(Equal("cf-*.pivotal"))
+		})
+
+		it("returns an error for a stale revision", func() {
+			_, err := provider.GetVersionInfo("stale", "pas")
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	when("product files reference variables", func() {
+		it.Before(func() {
+			err := ioutil.WriteFile(filepath.Join(versionRoot, "pas.yml"), []byte("version: 2.1.5\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\nstemcell_version: \"3586.36\"\nstemcell_file_pattern: \"*((iaas))*\"\n"), 0644)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		it("resolves them from vars", func() {
+			provider.Vars = map[string]string{"iaas": "vsphere"}
+			versionInfo, err := provider.GetVersionInfo("", "pas")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.StemcellFilePattern).Should(Equal("*vsphere*"))
+		})
+
+		it("returns an error when a variable is not set", func() {
+			_, err := provider.GetVersionInfo("", "pas")
+			Expect(err).Should(MatchError(ContainSubstring("pas.yml:5: unresolved variable ((iaas))")))
+		})
+	})
+
+	when("products inherit from other files", func() {
+		write := func(name, contents string) {
+			err := ioutil.WriteFile(filepath.Join(versionRoot, name), []byte(contents), 0644)
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+
+		it.Before(func() {
+			write("_defaults.yml", "stemcell_product: stemcells-ubuntu-xenial\nstemcell_version: \"97.28\"\nstemcell_file_pattern: \"*vsphere*\"\n")
+			write("isolation-segment.yml", "extends: pas\nproduct: p-isolation-segment\nfile_pattern: p-isolation-segment-*.pivotal\n")
+		})
+
+		it("merges _defaults and the products it extends", func() {
+			write("pas.yml", "version: 2.1.5\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\nstemcell_version: \"97.32\"\n")
+
+			versionInfo, err := provider.GetVersionInfo("", "isolation-segment")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(*versionInfo).Should(Equal(types.VersionInfo{
+				Version:             "2.1.5",
+				PivotalProduct:      "p-isolation-segment",
+				FilePattern:         "p-isolation-segment-*.pivotal",
+				StemcellProduct:     "stemcells-ubuntu-xenial",
+				StemcellVersion:     "97.32",
+				StemcellFilePattern: "*vsphere*",
+				Provenance: &types.Provenance{
+					Files: []string{
+						filepath.Join(versionRoot, "_defaults.yml"),
+						filepath.Join(versionRoot, "pas.yml"),
+						filepath.Join(versionRoot, "isolation-segment.yml"),
+					},
+					Fields: map[string]string{
+						"version":               filepath.Join(versionRoot, "pas.yml"),
+						"product":               filepath.Join(versionRoot, "isolation-segment.yml"),
+						"file_pattern":          filepath.Join(versionRoot, "isolation-segment.yml"),
+						"stemcell_product":      filepath.Join(versionRoot, "_defaults.yml"),
+						"stemcell_version":      filepath.Join(versionRoot, "pas.yml"),
+						"stemcell_file_pattern": filepath.Join(versionRoot, "_defaults.yml"),
+					},
+				},
+			}))
+		})
+
+		it("reports problems in the file they come from", func() {
+			write("_defaults.yml", "stemcell_version: \"97.28\"\nstemcell_file_pattern: \"*[vsphere*\"\n")
+
+			_, err := provider.GetVersionInfo("", "pas")
+			Expect(err).Should(MatchError(ContainSubstring("%s:2: stemcell_file_pattern", filepath.Join(versionRoot, "_defaults.yml"))))
+		})
+
+		it("returns an error when products extend each other", func() {
+			write("pas.yml", "extends: isolation-segment\nversion: 2.1.5\n")
+
+			_, err := provider.GetVersionInfo("", "isolation-segment")
+			Expect(err).Should(MatchError("product isolation-segment extends itself: isolation-segment -> pas -> isolation-segment"))
+		})
+	})
+
+	when("overlay roots are configured", func() {
+		var overlayRoot string
+		it.Before(func() {
+			overlayRoot = filepath.Join(versionRoot, "prod")
+			err := os.Mkdir(overlayRoot, 0755)
+			Expect(err).ShouldNot(HaveOccurred())
+			provider.OverlayRoots = []string{overlayRoot}
+		})
+
+		it("merges the product from each root, later roots overriding earlier ones", func() {
+			err := ioutil.WriteFile(filepath.Join(overlayRoot, "pas.yml"), []byte("version: 2.1.6\n"), 0644)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			versionInfo, err := provider.GetVersionInfo("", "pas")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.Version).Should(Equal("2.1.6"))
+			Expect(versionInfo.FilePattern).Should(Equal("cf-*.pivotal"))
+			Expect(versionInfo.Provenance.Fields["version"]).Should(Equal(filepath.Join(overlayRoot, "pas.yml")))
+			Expect(versionInfo.Provenance.Fields["file_pattern"]).Should(Equal(filepath.Join(versionRoot, "pas.yml")))
+		})
+
+		it("reads products that only exist in an overlay root", func() {
+			err := ioutil.WriteFile(filepath.Join(overlayRoot, "opsman.yml"), []byte("version: 2.1.3\nproduct: ops-manager\nfile_pattern: pcf-vsphere-*.ova\n"), 0644)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			versionInfo, err := provider.GetVersionInfo("", "opsman")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.PivotalProduct).Should(Equal("ops-manager"))
+		})
+
+		it("returns a new version when an overlay changes", func() {
+			originalVersion, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			err = ioutil.WriteFile(filepath.Join(overlayRoot, "pas.yml"), []byte("version: 2.1.6\n"), 0644)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			newVersion, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(newVersion.Ref).ShouldNot(Equal(originalVersion.Ref))
+		})
+	})
+
+	when("listing products", func() {
+		it("returns the products in each root, leaving out _defaults", func() {
+			overlayRoot := filepath.Join(versionRoot, "prod")
+			err := os.Mkdir(overlayRoot, 0755)
+			Expect(err).ShouldNot(HaveOccurred())
+			provider.OverlayRoots = []string{overlayRoot}
+			err = ioutil.WriteFile(filepath.Join(versionRoot, "_defaults.yml"), []byte("stemcell_product: stemcells-ubuntu-xenial\n"), 0644)
+			Expect(err).ShouldNot(HaveOccurred())
+			err = ioutil.WriteFile(filepath.Join(overlayRoot, "opsman.toml"), []byte("version = \"2.1.3\"\n"), 0644)
+			Expect(err).ShouldNot(HaveOccurred())
+			err = ioutil.WriteFile(filepath.Join(overlayRoot, "pas.yml"), []byte("version: 2.1.6\n"), 0644)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			products, err := provider.ListProducts("")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(products).Should(Equal([]string{"opsman", "pas"}))
+		})
+
+		it("returns an error for a stale revision", func() {
+			_, err := provider.ListProducts("stale")
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	when("product files use other formats", func() {
+		it.Before(func() {
+			os.Remove(filepath.Join(versionRoot, "pas.yml"))
+		})
+
+		it("reads .yaml product files", func() {
+			err := ioutil.WriteFile(filepath.Join(versionRoot, "pas.yaml"), []byte("version: 2.1.5\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\n"), 0644)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			versionInfo, err := provider.GetVersionInfo("", "pas")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.Version).Should(Equal("2.1.5"))
+		})
+
+		it("reads .json product files", func() {
+			err := ioutil.WriteFile(filepath.Join(versionRoot, "pas.json"), []byte(`{"version": "2.1.5", "product": "elastic-runtime", "file_pattern": "cf-*.pivotal"}`), 0644)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			versionInfo, err := provider.GetVersionInfo("", "pas")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.Version).Should(Equal("2.1.5"))
+			Expect(versionInfo.FilePattern).Should(Equal("cf-*.pivotal"))
+		})
+
+		it("reads .toml product files", func() {
+			err := ioutil.WriteFile(filepath.Join(versionRoot, "pas.toml"), []byte("version = \"2.1.5\"\nproduct = \"elastic-runtime\"\nfile_pattern = \"cf-*.pivotal\"\n"), 0644)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			versionInfo, err := provider.GetVersionInfo("", "pas")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.PivotalProduct).Should(Equal("elastic-runtime"))
+			Expect(versionInfo.FilePattern).Should(Equal("cf-*.pivotal"))
+		})
+
+		it("includes them in the version", func() {
+			err := ioutil.WriteFile(filepath.Join(versionRoot, "pas.json"), []byte(`{"version": "2.1.5"}`), 0644)
+			Expect(err).ShouldNot(HaveOccurred())
+			originalVersion, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			err = ioutil.WriteFile(filepath.Join(versionRoot, "pas.json"), []byte(`{"version": "2.1.6"}`), 0644)
+			Expect(err).ShouldNot(HaveOccurred())
+			newVersion, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(newVersion.Ref).ShouldNot(Equal(originalVersion.Ref))
+		})
+
+		it("returns an error when more than one file exists for the product", func() {
+			err := ioutil.WriteFile(filepath.Join(versionRoot, "pas.yaml"), []byte("version: 2.1.5\n"), 0644)
+			Expect(err).ShouldNot(HaveOccurred())
+			err = ioutil.WriteFile(filepath.Join(versionRoot, "pas.json"), []byte(`{"version": "2.1.5"}`), 0644)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			_, err = provider.GetVersionInfo("", "pas")
+			Expect(err).Should(MatchError(ContainSubstring("more than one configuration file for product pas found in %s: pas.yaml, pas.json", versionRoot)))
+		})
+
+		it("lists the file names tried when the product is missing", func() {
+			err := ioutil.WriteFile(filepath.Join(versionRoot, "opsman.yml"), []byte("version: 2.1.3\n"), 0644)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			_, err = provider.GetVersionInfo("", "pas")
+			Expect(err).Should(MatchError(ContainSubstring("tried pas.yml, pas.yaml, pas.json, pas.toml")))
+		})
+	})
+}
@@ -0,0 +1,8 @@
+package config
+
+import "golang.org/x/crypto/ssh"
+
+// HostKeyCallback - exposes the host key verification of the provider to tests
+func (provider *GitProvider) HostKeyCallback() (ssh.HostKeyCallback, error) {
+	return provider.hostKeyCallback()
+}
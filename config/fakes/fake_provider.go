@@ -16,6 +16,15 @@ type FakeProvider struct {
 		result1 *types.Version
 		result2 error
 	}
+	VersionsSinceStub        func(previous types.Version) ([]types.Version, error)
+	versionsSinceMutex       sync.RWMutex
+	versionsSinceArgsForCall []struct {
+		previous types.Version
+	}
+	versionsSinceReturns struct {
+		result1 []types.Version
+		result2 error
+	}
 	GetVersionInfoStub        func(revision, productName string) (*types.VersionInfo, error)
 	getVersionInfoMutex       sync.RWMutex
 	getVersionInfoArgsForCall []struct {
@@ -56,6 +65,40 @@ func (fake *FakeProvider) LatestVersionReturns(result1 *types.Version, result2 e
 	}{result1, result2}
 }
 
+func (fake *FakeProvider) VersionsSince(previous types.Version) ([]types.Version, error) {
+	fake.versionsSinceMutex.Lock()
+	fake.versionsSinceArgsForCall = append(fake.versionsSinceArgsForCall, struct {
+		previous types.Version
+	}{previous})
+	fake.recordInvocation("VersionsSince", []interface{}{previous})
+	fake.versionsSinceMutex.Unlock()
+	if fake.VersionsSinceStub != nil {
+		return fake.VersionsSinceStub(previous)
+	} else {
+		return fake.versionsSinceReturns.result1, fake.versionsSinceReturns.result2
+	}
+}
+
+func (fake *FakeProvider) VersionsSinceCallCount() int {
+	fake.versionsSinceMutex.RLock()
+	defer fake.versionsSinceMutex.RUnlock()
+	return len(fake.versionsSinceArgsForCall)
+}
+
+func (fake *FakeProvider) VersionsSinceArgsForCall(i int) types.Version {
+	fake.versionsSinceMutex.RLock()
+	defer fake.versionsSinceMutex.RUnlock()
+	return fake.versionsSinceArgsForCall[i].previous
+}
+
+func (fake *FakeProvider) VersionsSinceReturns(result1 []types.Version, result2 error) {
+	fake.VersionsSinceStub = nil
+	fake.versionsSinceReturns = struct {
+		result1 []types.Version
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeProvider) GetVersionInfo(revision string, productName string) (*types.VersionInfo, error) {
 	fake.getVersionInfoMutex.Lock()
 	fake.getVersionInfoArgsForCall = append(fake.getVersionInfoArgsForCall, struct {
@@ -96,6 +139,8 @@ func (fake *FakeProvider) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.latestVersionMutex.RLock()
 	defer fake.latestVersionMutex.RUnlock()
+	fake.versionsSinceMutex.RLock()
+	defer fake.versionsSinceMutex.RUnlock()
 	fake.getVersionInfoMutex.RLock()
 	defer fake.getVersionInfoMutex.RUnlock()
 	return fake.invocations
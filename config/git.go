@@ -1,198 +1,477 @@
 package config
 
 import (
-	"errors"
+	"crypto/sha256"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"os/exec"
-	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
 
-	"gopkg.in/yaml.v2"
+	git "gopkg.in/src-d/go-git.v4"
+	gitconfig "gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
 
 	"github.com/pivotalservices/file-downloader-resource/types"
 )
 
-var gitRepoDir string
-var privateKeyPath string
-var netRcPath string
-
-var ErrEncryptedKey = errors.New("private keys with passphrases are not supported")
+var defaultCacheDir string
 
 func init() {
-	gitRepoDir = filepath.Join(os.TempDir(), "file-downloader-git-repo")
-	privateKeyPath = filepath.Join(os.TempDir(), "private-key")
-	netRcPath = filepath.Join(os.Getenv("HOME"), ".netrc")
+	defaultCacheDir = filepath.Join(os.TempDir(), "file-downloader-git-repos")
+}
+
+// AuthError - returned when the repository rejects the configured credentials
+type AuthError struct {
+	URI string
+	Err error
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("authentication to %s failed: %s", e.URI, e.Err)
+}
+
+// BranchNotFoundError - returned when the branch does not exist in the repository
+type BranchNotFoundError struct {
+	URI    string
+	Branch string
+}
+
+func (e *BranchNotFoundError) Error() string {
+	return fmt.Sprintf("branch %s not found in %s", e.Branch, e.URI)
+}
+
+// RevisionNotFoundError - returned when the revision does not exist in the repository
+type RevisionNotFoundError struct {
+	URI      string
+	Revision string
+}
+
+func (e *RevisionNotFoundError) Error() string {
+	return fmt.Sprintf("revision %s not found in %s", e.Revision, e.URI)
 }
 
 type GitProvider struct {
-	VersionRoot string
-	URI         string
-	Branch      string
-	PrivateKey  string
-	Username    string
-	Password    string
-	Depth       string
-	Path        string
+	VersionRoot            string
+	OverlayRoots           []string
+	URI                    string
+	Branch                 string
+	PrivateKey             string
+	PrivateKeyPassphrase   string
+	KnownHosts             string
+	HostKeyFingerprint     string
+	Username               string
+	Password               string
+	AccessToken            string
+	Depth                  string
+	Path                   string
+	Paths                  []string
+	IgnorePaths            []string
+	TagFilter              string
+	CommitVerificationKeys []string
+	Product                string
+	Vars                   map[string]string
+	CacheDir               string
+
+	repo *git.Repository
 }
 
-//GetVersionInfo - Check returns version of git resource
+// GetVersionInfo - reads the product configuration from the tree of revision
 func (provider *GitProvider) GetVersionInfo(revision, productName string) (*types.VersionInfo, error) {
-	err := provider.setUpAuth()
+	versionInfo, err := provider.getVersionInfo(revision, productName)
+	return versionInfo, provider.scrub(err)
+}
+
+// LatestVersion - returns the newest first-parent commit on branch that changes path
+func (provider *GitProvider) LatestVersion() (*types.Version, error) {
+	version, err := provider.latestVersion()
+	return version, provider.scrub(err)
+}
+
+// VersionsSince - returns the first-parent commits on branch that change path, from previous to the newest
+func (provider *GitProvider) VersionsSince(previous types.Version) ([]types.Version, error) {
+	versions, err := provider.versionsSince(previous.Ref)
+	return versions, provider.scrub(err)
+}
+
+// ListProducts - returns the products with a file under version_root or an overlay root at revision
+func (provider *GitProvider) ListProducts(revision string) ([]string, error) {
+	products, err := provider.listProducts(revision)
+	return products, provider.scrub(err)
+}
+
+func (provider *GitProvider) listProducts(revision string) ([]string, error) {
+	tree, unlock, err := provider.treeAt(revision)
 	if err != nil {
 		return nil, err
 	}
+	defer unlock()
 
-	err = provider.setUpRepo(revision)
+	var files []string
+	err = tree.Files().ForEach(func(file *object.File) error {
+		files = append(files, file.Name)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	bytes, err := ioutil.ReadFile(path.Join(gitRepoDir, provider.VersionRoot, fmt.Sprintf("%s.yml", productName)))
+	return onlyProduct(productNamesOf(files, versionRoots(provider.VersionRoot, provider.OverlayRoots)), provider.Product), nil
+}
+
+func (provider *GitProvider) getVersionInfo(revision, productName string) (*types.VersionInfo, error) {
+	tree, unlock, err := provider.treeAt(revision)
 	if err != nil {
 		return nil, err
 	}
-	versionInfo := types.VersionInfo{}
+	defer unlock()
 
-	err = yaml.Unmarshal(bytes, &versionInfo)
+	return provider.productFilesAt(tree, fmt.Sprintf(" at revision %s", revision)).load(productName)
+}
 
-	return &versionInfo, err
+// productFilesAt - returns the product files in tree
+func (provider *GitProvider) productFilesAt(tree *object.Tree, location string) *productFiles {
+	return &productFiles{
+		roots:    versionRoots(provider.VersionRoot, provider.OverlayRoots),
+		location: location,
+		vars:     provider.Vars,
+		exists: func(name string) (bool, error) {
+			_, err := tree.File(name)
+			if err == object.ErrFileNotFound {
+				return false, nil
+			}
+			return err == nil, err
+		},
+		read: func(name string) ([]byte, error) {
+			file, err := tree.File(name)
+			if err != nil {
+				return nil, err
+			}
+			contents, err := file.Contents()
+			return []byte(contents), err
+		},
+	}
 }
 
-//LatestVersion - Check returns version of git resource
-func (provider *GitProvider) LatestVersion() (*types.Version, error) {
-	err := provider.setUpAuth()
+// treeAt - returns the tree of the verified commit at revision, and a func to unlock the workspace once done with it
+func (provider *GitProvider) treeAt(revision string) (*object.Tree, func(), error) {
+	repo, unlock, err := provider.repoAt(revision)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	err = provider.setUpRepo("HEAD")
+	commit, err := provider.commit(repo, revision)
 	if err != nil {
-		return nil, err
+		unlock()
+		return nil, nil, err
 	}
 
-	var gitVersion *exec.Cmd
-	if len(provider.Path) > 0 {
-		gitVersion = exec.Command("git", "log", "--format='%H'", "--first-parent", "-1", "--", provider.Path)
-	} else {
-		gitVersion = exec.Command("git", "log", "--format='%H'", "--first-parent", "-1")
+	err = provider.verifyRevision(repo, revision, commit)
+	if err != nil {
+		unlock()
+		return nil, nil, err
 	}
-	gitVersion.Dir = gitRepoDir
-	gitVersion.Stderr = os.Stderr
-	out, err := gitVersion.Output()
+
+	tree, err := commit.Tree()
+	if err != nil {
+		unlock()
+		return nil, nil, err
+	}
+
+	return tree, unlock, nil
+}
+
+func (provider *GitProvider) latestVersion() (*types.Version, error) {
+	versions, err := provider.versionsSince("")
 	if err != nil {
 		return nil, err
 	}
-	return &types.Version{Ref: strings.Replace(strings.Replace(string(out), "\n", "", -1), "'", "", -1)}, nil
+
+	return &versions[len(versions)-1], nil
 }
 
-func (provider *GitProvider) setUpRepo(revision string) error {
-	_, err := os.Stat(gitRepoDir)
+func (provider *GitProvider) versionsSince(previousRef string) ([]types.Version, error) {
+	repo, unlock, err := provider.setUpRepo()
 	if err != nil {
-		gitClone := exec.Command("git", "clone", provider.URI, "--branch", provider.Branch)
-		if len(provider.Depth) > 0 {
-			gitClone.Args = append(gitClone.Args, "--depth", provider.Depth)
+		return nil, err
+	}
+	defer unlock()
+
+	if len(provider.TagFilter) > 0 {
+		tags, err := provider.tagVersions(repo)
+		if err != nil {
+			return nil, err
 		}
-		gitClone.Args = append(gitClone.Args, "--single-branch", gitRepoDir)
-		gitClone.Stdout = os.Stderr
-		gitClone.Stderr = os.Stderr
-		if err := gitClone.Run(); err != nil {
-			return err
+		return versionsFrom(tags, previousRef), nil
+	}
+
+	var (
+		commits  []*object.Commit
+		last     *object.Commit
+		previous *object.Commit
+	)
+	err = provider.firstParentLog(repo, func(commit *object.Commit, changed bool) bool {
+		last = commit
+		if commit.Hash.String() == previousRef {
+			previous = commit
+			return false
 		}
-	} else {
-		gitFetch := exec.Command("git", "fetch", "origin", provider.Branch)
-		gitFetch.Dir = gitRepoDir
-		gitFetch.Stdout = os.Stderr
-		gitFetch.Stderr = os.Stderr
-		if err := gitFetch.Run(); err != nil {
-			return err
+		if changed {
+			commits = append(commits, commit)
 		}
+		return len(previousRef) > 0 || !changed
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	gitReset := exec.Command("git", "reset", "--hard", "origin/"+provider.Branch)
-	gitReset.Dir = gitRepoDir
-	gitReset.Stdout = os.Stderr
-	gitReset.Stderr = os.Stderr
-	if err := gitReset.Run(); err != nil {
-		return err
+	// the previous version is unknown or no longer on the branch, so start again from the latest
+	if previous == nil {
+		if len(commits) > 0 {
+			return []types.Version{{Ref: commits[0].Hash.String()}}, nil
+		}
+		if provider.verifying() {
+			return nil, fmt.Errorf("no commits signed by the commit_verification_keys found on branch %s", provider.Branch)
+		}
+		return []types.Version{{Ref: last.Hash.String()}}, nil
 	}
 
-	gitCheckout := exec.Command("git", "checkout", "-q", revision)
-	gitCheckout.Dir = gitRepoDir
-	gitCheckout.Stdout = os.Stderr
-	gitCheckout.Stderr = os.Stderr
-	if err := gitCheckout.Run(); err != nil {
-		return err
+	versions := []types.Version{{Ref: previous.Hash.String()}}
+	for i := len(commits) - 1; i >= 0; i-- {
+		versions = append(versions, types.Version{Ref: commits[i].Hash.String()})
 	}
 
-	return nil
+	return versions, nil
 }
 
-func (provider *GitProvider) setUpAuth() error {
-	_, err := os.Stat(netRcPath)
+// firstParentLog - calls fn for each first-parent commit from the tip of branch until fn returns false,
+// where changed is only true for commits that change paths and are signed when verification is enabled
+func (provider *GitProvider) firstParentLog(repo *git.Repository, fn func(commit *object.Commit, changed bool) bool) error {
+	err := provider.validatePaths()
 	if err != nil {
-		if !os.IsNotExist(err) {
-			return err
-		}
-	} else {
-		err := os.Remove(netRcPath)
+		return err
+	}
+
+	ref, err := repo.Reference(plumbing.NewRemoteReferenceName(git.DefaultRemoteName, provider.Branch), true)
+	if err != nil {
+		return &BranchNotFoundError{URI: provider.redactedURI(), Branch: provider.Branch}
+	}
+
+	commit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return err
+	}
+
+	for commit != nil {
+		parent, err := firstParent(commit)
 		if err != nil {
 			return err
 		}
-	}
-
-	if len(provider.PrivateKey) > 0 {
-		err := provider.setUpKey()
+		changed, err := provider.changesPaths(commit, parent)
 		if err != nil {
 			return err
 		}
+		if changed && provider.verifying() {
+			changed = provider.signedByAny(commit.Verify)
+		}
+		if !fn(commit, changed) {
+			return nil
+		}
+		commit = parent
 	}
 
-	if len(provider.Username) > 0 && len(provider.Password) > 0 {
-		err := provider.setUpUsernamePassword()
+	return nil
+}
+
+// repoAt - returns the workspace with the commit at revision, which stays locked until unlock is called. The
+// repository this provider already fetched is reused when it has the commit, so that in reads the product and
+// the commit info of a version with a single fetch
+func (provider *GitProvider) repoAt(revision string) (*git.Repository, func(), error) {
+	if provider.repo != nil {
+		unlock, err := lockWorkspace(provider.workspace())
 		if err != nil {
-			return err
+			return nil, nil, err
+		}
+		if commit, err := provider.commit(provider.repo, revision); err == nil && commit.Hash.String() == revision {
+			return provider.repo, unlock, nil
 		}
+		unlock()
 	}
 
-	return nil
+	return provider.setUpRepo()
 }
 
-func (provider *GitProvider) setUpKey() error {
-	if strings.Contains(provider.PrivateKey, "ENCRYPTED") {
-		return ErrEncryptedKey
+// setUpRepo - clones or fetches the workspace for uri and branch, which stays locked until unlock is called
+func (provider *GitProvider) setUpRepo() (*git.Repository, func(), error) {
+	auth, err := provider.auth()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = provider.validateVerificationKeys()
+	if err != nil {
+		return nil, nil, err
 	}
 
-	_, err := os.Stat(privateKeyPath)
+	workspace := provider.workspace()
+	unlock, err := lockWorkspace(workspace)
 	if err != nil {
-		if os.IsNotExist(err) {
-			err = ioutil.WriteFile(privateKeyPath, []byte(provider.PrivateKey), 0600)
+		return nil, nil, err
+	}
+
+	repo, err := provider.openRepo(workspace, auth)
+	if err != nil {
+		unlock()
+		return nil, nil, err
+	}
+	provider.repo = repo
+
+	return repo, unlock, nil
+}
+
+func (provider *GitProvider) openRepo(workspace string, auth transport.AuthMethod) (*git.Repository, error) {
+	repo, err := git.PlainOpen(workspace)
+	if err == nil && !hasRemoteURL(repo, provider.URI) {
+		err = os.RemoveAll(workspace)
+		if err != nil {
+			return nil, err
+		}
+		err = git.ErrRepositoryNotExists
+	}
+	if err == git.ErrRepositoryNotExists {
+		cloneOptions := &git.CloneOptions{
+			URL:           provider.URI,
+			Auth:          auth,
+			ReferenceName: plumbing.NewBranchReferenceName(provider.Branch),
+			SingleBranch:  true,
+			Progress:      os.Stderr,
+		}
+		if len(provider.Depth) > 0 {
+			cloneOptions.Depth, err = strconv.Atoi(provider.Depth)
 			if err != nil {
-				return err
+				return nil, fmt.Errorf("invalid depth %s: %s", provider.Depth, err)
 			}
-		} else {
-			return err
 		}
+		repo, err = git.PlainClone(workspace, true, cloneOptions)
+		if err != nil {
+			os.RemoveAll(workspace)
+			return nil, provider.gitError(err)
+		}
+		if len(provider.TagFilter) == 0 {
+			return repo, nil
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	refSpecs := []gitconfig.RefSpec{
+		gitconfig.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/%s/%s", provider.Branch, git.DefaultRemoteName, provider.Branch)),
+	}
+	if len(provider.TagFilter) > 0 {
+		refSpecs = append(refSpecs, gitconfig.RefSpec("+refs/tags/*:refs/tags/*"))
+	}
+
+	err = repo.Fetch(&git.FetchOptions{
+		RemoteName: git.DefaultRemoteName,
+		RefSpecs:   refSpecs,
+		Auth:       auth,
+		Progress:   os.Stderr,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return nil, provider.gitError(err)
+	}
+
+	return repo, nil
+}
+
+// workspace - returns the clone directory for the uri and branch of the provider
+func (provider *GitProvider) workspace() string {
+	cacheDir := provider.CacheDir
+	if len(cacheDir) == 0 {
+		cacheDir = defaultCacheDir
 	}
+	key := sha256.Sum256([]byte(provider.URI + "\x00" + provider.Branch))
 
-	return os.Setenv("GIT_SSH_COMMAND", "ssh -o StrictHostKeyChecking=no -i "+privateKeyPath)
+	return filepath.Join(cacheDir, fmt.Sprintf("%x", key[:8]))
 }
 
-func (provider *GitProvider) setUpUsernamePassword() error {
-	_, err := os.Stat(netRcPath)
+func lockWorkspace(workspace string) (func(), error) {
+	err := os.MkdirAll(filepath.Dir(workspace), 0700)
 	if err != nil {
-		if os.IsNotExist(err) {
-			content := fmt.Sprintf("default login %s password %s", provider.Username, provider.Password)
-			err = ioutil.WriteFile(netRcPath, []byte(content), 0600)
-			if err != nil {
-				return err
-			}
-		} else {
-			return err
+		return nil, err
+	}
+
+	lockFile, err := os.OpenFile(workspace+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	err = syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX)
+	if err != nil {
+		lockFile.Close()
+		return nil, fmt.Errorf("failed to lock %s: %s", workspace, err)
+	}
+
+	return func() {
+		syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
+		lockFile.Close()
+	}, nil
+}
+
+func hasRemoteURL(repo *git.Repository, uri string) bool {
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return false
+	}
+	urls := remote.Config().URLs
+
+	return len(urls) > 0 && urls[0] == uri
+}
+
+func (provider *GitProvider) commit(repo *git.Repository, revision string) (*object.Commit, error) {
+	hash, err := repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return nil, &RevisionNotFoundError{URI: provider.redactedURI(), Revision: revision}
+	}
+
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		if err == plumbing.ErrObjectNotFound {
+			return nil, &RevisionNotFoundError{URI: provider.redactedURI(), Revision: revision}
 		}
+		return nil, err
 	}
 
-	return nil
+	return commit, nil
+}
+
+func (provider *GitProvider) gitError(err error) error {
+	switch {
+	case err == transport.ErrAuthenticationRequired,
+		err == transport.ErrAuthorizationFailed,
+		strings.Contains(err.Error(), "unable to authenticate"):
+		return &AuthError{URI: provider.redactedURI(), Err: provider.scrub(err)}
+	case err == plumbing.ErrReferenceNotFound,
+		strings.HasPrefix(err.Error(), "couldn't find remote ref"):
+		return &BranchNotFoundError{URI: provider.redactedURI(), Branch: provider.Branch}
+	default:
+		return err
+	}
+}
+
+// firstParent returns nil for root commits and for the boundary of a shallow clone
+func firstParent(commit *object.Commit) (*object.Commit, error) {
+	if commit.NumParents() == 0 {
+		return nil, nil
+	}
+
+	parent, err := commit.Parent(0)
+	if err == plumbing.ErrObjectNotFound {
+		return nil, nil
+	}
+
+	return parent, err
 }
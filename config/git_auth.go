@@ -0,0 +1,198 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	gitssh "gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+)
+
+const redacted = "*****"
+
+var ErrEncryptedKey = errors.New("private key is encrypted but no private_key_passphrase was provided")
+
+// auth - returns credentials that are only used by the git operations of this provider
+func (provider *GitProvider) auth() (transport.AuthMethod, error) {
+	if len(provider.PrivateKey) > 0 {
+		user := "git"
+		endpoint, err := transport.NewEndpoint(provider.URI)
+		if err == nil && len(endpoint.User) > 0 {
+			user = endpoint.User
+		}
+
+		var signer ssh.Signer
+		if len(provider.PrivateKeyPassphrase) > 0 {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(provider.PrivateKey), []byte(provider.PrivateKeyPassphrase))
+		} else if strings.Contains(provider.PrivateKey, "ENCRYPTED") {
+			return nil, ErrEncryptedKey
+		} else {
+			signer, err = ssh.ParsePrivateKey([]byte(provider.PrivateKey))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %s", err)
+		}
+
+		hostKeyCallback, err := provider.hostKeyCallback()
+		if err != nil {
+			return nil, err
+		}
+
+		return &gitssh.PublicKeys{
+			User:   user,
+			Signer: signer,
+			HostKeyCallbackHelper: gitssh.HostKeyCallbackHelper{
+				HostKeyCallback: hostKeyCallback,
+			},
+		}, nil
+	}
+
+	if len(provider.AccessToken) > 0 {
+		username := provider.Username
+		if len(username) == 0 {
+			username = tokenUsername(provider.URI)
+		}
+		return &githttp.BasicAuth{
+			Username: username,
+			Password: provider.AccessToken,
+		}, nil
+	}
+
+	if len(provider.Username) > 0 && len(provider.Password) > 0 {
+		return &githttp.BasicAuth{
+			Username: provider.Username,
+			Password: provider.Password,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// hostKeyCallback - verifies the host key against known_hosts and host_key_fingerprint when they are set
+func (provider *GitProvider) hostKeyCallback() (ssh.HostKeyCallback, error) {
+	if len(provider.KnownHosts) == 0 && len(provider.HostKeyFingerprint) == 0 {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	var callbacks []ssh.HostKeyCallback
+	if len(provider.KnownHosts) > 0 {
+		callback, err := knownHostsCallback(provider.KnownHosts)
+		if err != nil {
+			return nil, err
+		}
+		callbacks = append(callbacks, callback)
+	}
+	if len(provider.HostKeyFingerprint) > 0 {
+		callbacks = append(callbacks, fingerprintCallback(provider.HostKeyFingerprint))
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		for _, callback := range callbacks {
+			err := callback(hostname, remote, key)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+func knownHostsCallback(knownHosts string) (ssh.HostKeyCallback, error) {
+	knownHostsFile, err := ioutil.TempFile("", "known-hosts")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(knownHostsFile.Name())
+	defer knownHostsFile.Close()
+
+	_, err = knownHostsFile.WriteString(knownHosts)
+	if err != nil {
+		return nil, err
+	}
+
+	callback, err := knownhosts.New(knownHostsFile.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse known_hosts: %s", err)
+	}
+
+	return callback, nil
+}
+
+func fingerprintCallback(fingerprint string) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		sha256Fingerprint := ssh.FingerprintSHA256(key)
+		if fingerprint == sha256Fingerprint || fingerprint == strings.TrimPrefix(sha256Fingerprint, "SHA256:") {
+			return nil
+		}
+		md5Fingerprint := ssh.FingerprintLegacyMD5(key)
+		if strings.TrimPrefix(fingerprint, "MD5:") == md5Fingerprint {
+			return nil
+		}
+
+		return fmt.Errorf("host key for %s has fingerprint %s, expected %s", hostname, sha256Fingerprint, fingerprint)
+	}
+}
+
+// tokenUsername - returns the username that git hosts expect alongside an access token
+func tokenUsername(uri string) string {
+	endpoint, err := transport.NewEndpoint(uri)
+	if err != nil {
+		return "x-access-token"
+	}
+
+	switch {
+	case strings.Contains(endpoint.Host, "gitlab"):
+		return "oauth2"
+	case strings.Contains(endpoint.Host, "bitbucket"):
+		return "x-token-auth"
+	default:
+		return "x-access-token"
+	}
+}
+
+// redactedURI - returns the uri without any password it contains
+func (provider *GitProvider) redactedURI() string {
+	parsed, err := url.Parse(provider.URI)
+	if err != nil || parsed.User == nil {
+		return provider.URI
+	}
+	if _, ok := parsed.User.Password(); ok {
+		parsed.User = url.UserPassword(parsed.User.Username(), redacted)
+	}
+
+	return parsed.String()
+}
+
+// scrub - removes credentials from the message of err
+func (provider *GitProvider) scrub(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	secrets := []string{provider.Password, provider.AccessToken}
+	if parsed, parseErr := url.Parse(provider.URI); parseErr == nil && parsed.User != nil {
+		if password, ok := parsed.User.Password(); ok {
+			secrets = append(secrets, password, url.QueryEscape(password))
+		}
+	}
+
+	message := err.Error()
+	for _, secret := range secrets {
+		if len(secret) > 0 {
+			message = strings.Replace(message, secret, redacted, -1)
+		}
+	}
+	if message == err.Error() {
+		return err
+	}
+
+	return errors.New(message)
+}
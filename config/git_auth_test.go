@@ -0,0 +1,116 @@
+package config_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/file-downloader-resource/config"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestHostKeyVerification(t *testing.T) {
+	spec.Run(t, "HostKeyVerification", testHostKeyVerification, spec.Report(report.Terminal{}))
+}
+
+func testHostKeyVerification(t *testing.T, when spec.G, it spec.S) {
+	var (
+		provider  *config.GitProvider
+		hostKey   ssh.PublicKey
+		otherKey  ssh.PublicKey
+		remote    net.Addr
+		hostname  = "github.com:22"
+		verifyKey = func(key ssh.PublicKey) error {
+			callback, err := provider.HostKeyCallback()
+			Expect(err).ShouldNot(HaveOccurred())
+			return callback(hostname, remote, key)
+		}
+	)
+	it.Before(func() {
+		RegisterTestingT(t)
+		provider = &config.GitProvider{URI: "git@github.com:pivotalservices/file-downloader-resource.git"}
+		hostKey = newPublicKey()
+		otherKey = newPublicKey()
+		remote = &net.TCPAddr{IP: net.ParseIP("140.82.112.3"), Port: 22}
+	})
+
+	when("known_hosts is set", func() {
+		it("accepts the host key it lists", func() {
+			provider.KnownHosts = knownhosts.Line([]string{"github.com"}, hostKey)
+			Expect(verifyKey(hostKey)).Should(Succeed())
+		})
+
+		it("rejects a different host key", func() {
+			provider.KnownHosts = knownhosts.Line([]string{"github.com"}, hostKey)
+			Expect(verifyKey(otherKey)).ShouldNot(Succeed())
+		})
+
+		it("rejects a host it doesn't list", func() {
+			provider.KnownHosts = knownhosts.Line([]string{"gitlab.com"}, hostKey)
+			Expect(verifyKey(hostKey)).ShouldNot(Succeed())
+		})
+	})
+
+	when("host_key_fingerprint is set", func() {
+		it("accepts a matching SHA256 fingerprint, with or without its prefix", func() {
+			provider.HostKeyFingerprint = ssh.FingerprintSHA256(hostKey)
+			Expect(verifyKey(hostKey)).Should(Succeed())
+
+			provider.HostKeyFingerprint = strings.TrimPrefix(ssh.FingerprintSHA256(hostKey), "SHA256:")
+			Expect(verifyKey(hostKey)).Should(Succeed())
+		})
+
+		it("accepts a matching MD5 fingerprint, with or without its prefix", func() {
+			provider.HostKeyFingerprint = ssh.FingerprintLegacyMD5(hostKey)
+			Expect(verifyKey(hostKey)).Should(Succeed())
+
+			provider.HostKeyFingerprint = "MD5:" + ssh.FingerprintLegacyMD5(hostKey)
+			Expect(verifyKey(hostKey)).Should(Succeed())
+		})
+
+		it("rejects a wrong SHA256 fingerprint", func() {
+			provider.HostKeyFingerprint = ssh.FingerprintSHA256(otherKey)
+			Expect(verifyKey(hostKey)).Should(MatchError(ContainSubstring("expected " + ssh.FingerprintSHA256(otherKey))))
+		})
+
+		it("rejects a wrong MD5 fingerprint", func() {
+			provider.HostKeyFingerprint = ssh.FingerprintLegacyMD5(otherKey)
+			Expect(verifyKey(hostKey)).ShouldNot(Succeed())
+		})
+	})
+
+	when("known_hosts and host_key_fingerprint are both set", func() {
+		it("accepts a host key that passes both", func() {
+			provider.KnownHosts = knownhosts.Line([]string{"github.com"}, hostKey)
+			provider.HostKeyFingerprint = ssh.FingerprintSHA256(hostKey)
+			Expect(verifyKey(hostKey)).Should(Succeed())
+		})
+
+		it("rejects a host key that only matches known_hosts", func() {
+			provider.KnownHosts = knownhosts.Line([]string{"github.com"}, hostKey)
+			provider.HostKeyFingerprint = ssh.FingerprintSHA256(otherKey)
+			Expect(verifyKey(hostKey)).ShouldNot(Succeed())
+		})
+
+		it("rejects a host key that only matches the fingerprint", func() {
+			provider.KnownHosts = knownhosts.Line([]string{"github.com"}, otherKey)
+			provider.HostKeyFingerprint = ssh.FingerprintSHA256(hostKey)
+			Expect(verifyKey(hostKey)).ShouldNot(Succeed())
+		})
+	})
+}
+
+func newPublicKey() ssh.PublicKey {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ShouldNot(HaveOccurred())
+	key, err := ssh.NewPublicKey(&private.PublicKey)
+	Expect(err).ShouldNot(HaveOccurred())
+	return key
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pivotalservices/file-downloader-resource/types"
+)
+
+// CommitInfoProvider - implemented by providers that can describe the change behind a version
+type CommitInfoProvider interface {
+	GetCommitInfo(revision string) (*types.CommitInfo, error)
+}
+
+// GetCommitInfo - returns the author, date, subject and changed product files of the commit at revision
+func (provider *GitProvider) GetCommitInfo(revision string) (*types.CommitInfo, error) {
+	commitInfo, err := provider.getCommitInfo(revision)
+	return commitInfo, provider.scrub(err)
+}
+
+func (provider *GitProvider) getCommitInfo(revision string) (*types.CommitInfo, error) {
+	repo, unlock, err := provider.repoAt(revision)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	commit, err := provider.commit(repo, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	parent, err := firstParent(commit)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := changedFiles(commit, parent)
+	if err != nil {
+		return nil, err
+	}
+
+	changedProducts := []string{}
+	for _, file := range files {
+		if provider.isProductFile(file) {
+			changedProducts = append(changedProducts, file)
+		}
+	}
+
+	return &types.CommitInfo{
+		Ref:             commit.Hash.String(),
+		Author:          fmt.Sprintf("%s <%s>", commit.Author.Name, commit.Author.Email),
+		Date:            commit.Author.When.Format(time.RFC3339),
+		Subject:         strings.SplitN(strings.TrimSpace(commit.Message), "\n", 2)[0],
+		ChangedProducts: changedProducts,
+	}, nil
+}
+
+// isProductFile - reports whether file is a product configuration directly under version_root or an overlay root
+func (provider *GitProvider) isProductFile(file string) bool {
+	return inRoots(file, versionRoots(provider.VersionRoot, provider.OverlayRoots)) && isProductFileName(file)
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// changesPaths - reports whether commit changes a file that matches paths and not ignore_paths,
+// and that is one of the files product is merged from at commit when it is set
+func (provider *GitProvider) changesPaths(commit, parent *object.Commit) (bool, error) {
+	paths := provider.paths()
+	if len(paths) == 0 && len(provider.Product) == 0 && len(provider.IgnorePaths) == 0 {
+		return true, nil
+	}
+
+	files, err := changedFiles(commit, parent)
+	if err != nil {
+		return false, err
+	}
+
+	var productPaths []string
+	if len(provider.Product) > 0 {
+		tree, err := commit.Tree()
+		if err != nil {
+			return false, err
+		}
+		productPaths = provider.productPaths(provider.productFilesAt(tree, "").chain(provider.Product))
+	}
+
+	for _, file := range files {
+		if len(paths) > 0 && !matchesAny(file, paths) {
+			continue
+		}
+		if len(productPaths) > 0 && !matchesAny(file, productPaths) {
+			continue
+		}
+		if matchesAny(file, provider.IgnorePaths) {
+			continue
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (provider *GitProvider) validatePaths() error {
+	patterns := append(provider.paths(), provider.IgnorePaths...)
+	if len(provider.Product) > 0 {
+		patterns = append(patterns, provider.productPaths([]string{provider.Product})...)
+	}
+	for _, pattern := range patterns {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return fmt.Errorf("invalid path pattern %s: %s", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+func (provider *GitProvider) paths() []string {
+	var paths []string
+	if len(provider.Path) > 0 {
+		paths = append(paths, provider.Path)
+	}
+
+	return append(paths, provider.Paths...)
+}
+
+// productPaths - returns the files of the products in each root
+func (provider *GitProvider) productPaths(products []string) []string {
+	var paths []string
+	for _, root := range versionRoots(provider.VersionRoot, provider.OverlayRoots) {
+		for _, product := range products {
+			for _, name := range productFileNames(product) {
+				paths = append(paths, path.Join(root, name))
+			}
+		}
+	}
+
+	return paths
+}
+
+// changedFiles - returns the files that differ between commit and its parent
+func changedFiles(commit, parent *object.Commit) ([]string, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	var parentTree *object.Tree
+	if parent != nil {
+		parentTree, err = parent.Tree()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, change := range changes {
+		if len(change.From.Name) > 0 {
+			files = append(files, change.From.Name)
+		}
+		if len(change.To.Name) > 0 && change.To.Name != change.From.Name {
+			files = append(files, change.To.Name)
+		}
+	}
+
+	return files, nil
+}
+
+// matchesAny - reports whether file, or a directory containing it, matches any of the glob patterns
+func matchesAny(file string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.Trim(pattern, "/")
+		if len(pattern) == 0 || pattern == "." {
+			return true
+		}
+		for candidate := file; candidate != "."; candidate = path.Dir(candidate) {
+			if matched, _ := path.Match(pattern, candidate); matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+
+	"github.com/pivotalservices/file-downloader-resource/types"
+)
+
+// tagVersions - returns the annotated tags matching tag_filter, oldest first
+func (provider *GitProvider) tagVersions(repo *git.Repository) ([]types.Version, error) {
+	_, err := path.Match(provider.TagFilter, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag_filter %s: %s", provider.TagFilter, err)
+	}
+
+	refs, err := repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []*object.Tag
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if matched, _ := path.Match(provider.TagFilter, ref.Name().Short()); !matched {
+			return nil
+		}
+		tag, err := repo.TagObject(ref.Hash())
+		if err == plumbing.ErrObjectNotFound {
+			// lightweight tags have no tag object
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if provider.verifying() && !provider.signedByAny(tag.Verify) {
+			return nil
+		}
+		tags = append(tags, tag)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		if provider.verifying() {
+			return nil, fmt.Errorf("no annotated tags matching %s signed by the commit_verification_keys found in %s", provider.TagFilter, provider.redactedURI())
+		}
+		return nil, fmt.Errorf("no annotated tags matching %s found in %s", provider.TagFilter, provider.redactedURI())
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Tagger.When.Equal(tags[j].Tagger.When) {
+			return tags[i].Name < tags[j].Name
+		}
+		return tags[i].Tagger.When.Before(tags[j].Tagger.When)
+	})
+
+	versions := []types.Version{}
+	for _, tag := range tags {
+		versions = append(versions, types.Version{Ref: tag.Name})
+	}
+
+	return versions, nil
+}
+
+// versionsFrom - returns versions from previous onwards, or only the latest when previous is not found
+func versionsFrom(versions []types.Version, previousRef string) []types.Version {
+	for i, version := range versions {
+		if version.Ref == previousRef {
+			return versions[i:]
+		}
+	}
+
+	return versions[len(versions)-1:]
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// UnverifiedRevisionError - returned when a revision is not signed by one of the commit verification keys
+type UnverifiedRevisionError struct {
+	URI      string
+	Revision string
+}
+
+func (e *UnverifiedRevisionError) Error() string {
+	return fmt.Sprintf("revision %s in %s is not signed by any of the commit_verification_keys", e.Revision, e.URI)
+}
+
+func (provider *GitProvider) verifying() bool {
+	return len(provider.CommitVerificationKeys) > 0
+}
+
+func (provider *GitProvider) validateVerificationKeys() error {
+	for i, key := range provider.CommitVerificationKeys {
+		_, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key))
+		if err != nil {
+			return fmt.Errorf("invalid commit_verification_keys entry %d: %s", i, err)
+		}
+	}
+
+	return nil
+}
+
+// signedByAny - reports whether verify succeeds with any of the commit verification keys
+func (provider *GitProvider) signedByAny(verify func(armoredKeyRing string) (*openpgp.Entity, error)) bool {
+	for _, key := range provider.CommitVerificationKeys {
+		_, err := verify(key)
+		if err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// verifyRevision - checks that the tag named revision, or else commit, is signed by a commit verification key
+func (provider *GitProvider) verifyRevision(repo *git.Repository, revision string, commit *object.Commit) error {
+	if !provider.verifying() {
+		return nil
+	}
+
+	ref, err := repo.Tag(revision)
+	if err == nil {
+		tag, err := repo.TagObject(ref.Hash())
+		if err == nil && provider.signedByAny(tag.Verify) {
+			return nil
+		}
+	}
+
+	if provider.signedByAny(commit.Verify) {
+		return nil
+	}
+
+	return &UnverifiedRevisionError{URI: provider.redactedURI(), Revision: revision}
+}
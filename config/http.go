@@ -0,0 +1,233 @@
+package config
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/pivotalservices/file-downloader-resource/types"
+)
+
+const refPlaceholder = "{ref}"
+
+// productRefSeparator - separates the ref of the manifest from the digest of the product in the version of a product
+const productRefSeparator = ":"
+
+// HTTPProvider - reads product configuration from a manifest served over http
+type HTTPProvider struct {
+	URI          string
+	VersionedURI string
+	Username     string
+	Password     string
+	Product      string
+	Vars         map[string]string
+	HTTPClient   *http.Client
+}
+
+// NewHTTPProvider - creates a provider for the manifest at uri
+func NewHTTPProvider(uri, versionedURI, username, password string, skipSSLValidation bool) *HTTPProvider {
+	return &HTTPProvider{
+		URI:          uri,
+		VersionedURI: versionedURI,
+		Username:     username,
+		Password:     password,
+		HTTPClient: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					InsecureSkipVerify: skipSSLValidation,
+				},
+				Proxy: http.ProxyFromEnvironment,
+			},
+		},
+	}
+}
+
+// GetVersionInfo - returns the version info for a product from the manifest at revision
+func (provider *HTTPProvider) GetVersionInfo(revision, productName string) (*types.VersionInfo, error) {
+	bytes, err := provider.manifestAt(revision)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := map[string]types.VersionInfo{}
+	err = yaml.Unmarshal(bytes, &manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	versionInfo, ok := manifest[productName]
+	if !ok {
+		return nil, fmt.Errorf("product %s not found in manifest at revision %s", productName, revision)
+	}
+
+	var document map[string]interface{}
+	err = yaml.Unmarshal(bytes, &document)
+	if err != nil {
+		return nil, err
+	}
+	problems := interpolate(&versionInfo, provider.Vars, nil)
+	if len(problems) > 0 {
+		return nil, &ProductFileError{File: fmt.Sprintf("%s in manifest at revision %s", productName, revision), Problems: problems}
+	}
+	err = validateDecoded(fmt.Sprintf("%s in manifest", productName), document[productName], &versionInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &versionInfo, nil
+}
+
+// ListProducts - returns the products in the manifest at revision
+func (provider *HTTPProvider) ListProducts(revision string) ([]string, error) {
+	bytes, err := provider.manifestAt(revision)
+	if err != nil {
+		return nil, err
+	}
+
+	var document map[string]interface{}
+	err = yaml.Unmarshal(bytes, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name := range document {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// manifestAt - fetches the manifest at revision, from versioned_uri when uri has moved on and, for the version
+// of a product, the product has changed
+func (provider *HTTPProvider) manifestAt(revision string) ([]byte, error) {
+	revision, digest := provider.splitRef(revision)
+	ref, bytes, err := provider.fetch(provider.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	if ref != revision && len(digest) > 0 {
+		if current, err := productDigest(bytes, provider.Product); err == nil && current == digest {
+			return bytes, nil
+		}
+	}
+	if ref != revision {
+		if len(provider.VersionedURI) == 0 {
+			return nil, fmt.Errorf("revision %s is no longer served by %s (current revision is %s) and no versioned_uri is configured", revision, provider.URI, ref)
+		}
+		_, bytes, err = provider.fetch(strings.Replace(provider.VersionedURI, refPlaceholder, url.PathEscape(revision), -1))
+		if err != nil {
+			return nil, fmt.Errorf("fetching revision %s: %s", revision, err)
+		}
+	}
+
+	return bytes, nil
+}
+
+// LatestVersion - returns the ETag of the manifest, or a digest of its content, followed by a digest of the
+// entry of the product when one is set
+func (provider *HTTPProvider) LatestVersion() (*types.Version, error) {
+	ref, bytes, err := provider.fetch(provider.URI)
+	if err != nil {
+		return nil, err
+	}
+	if len(provider.Product) > 0 {
+		digest, err := productDigest(bytes, provider.Product)
+		if err != nil {
+			return nil, err
+		}
+		ref += productRefSeparator + digest
+	}
+
+	return &types.Version{Ref: ref}, nil
+}
+
+// VersionsSince - returns the latest version only, as earlier manifests can't be listed. When a product is set,
+// previous is returned until the entry of the product changes
+func (provider *HTTPProvider) VersionsSince(previous types.Version) ([]types.Version, error) {
+	if len(provider.Product) == 0 || len(previous.Ref) == 0 {
+		return latestVersionOnly(provider)
+	}
+
+	latest, err := provider.LatestVersion()
+	if err != nil {
+		return nil, err
+	}
+	_, previousDigest := provider.splitRef(previous.Ref)
+	_, latestDigest := provider.splitRef(latest.Ref)
+	if previousDigest == latestDigest {
+		return []types.Version{previous}, nil
+	}
+
+	return []types.Version{*latest}, nil
+}
+
+// splitRef - splits the version of a product into the ref of the manifest and the digest of the product
+func (provider *HTTPProvider) splitRef(ref string) (string, string) {
+	i := strings.LastIndex(ref, productRefSeparator)
+	if len(provider.Product) == 0 || i < 0 {
+		return ref, ""
+	}
+
+	return ref[:i], ref[i+len(productRefSeparator):]
+}
+
+// productDigest - returns a digest of the entry of product in manifest, which only changes with the entry
+func productDigest(manifest []byte, product string) (string, error) {
+	var document map[string]interface{}
+	err := yaml.Unmarshal(manifest, &document)
+	if err != nil {
+		return "", err
+	}
+	entry, ok := document[product]
+	if !ok {
+		return "", fmt.Errorf("product %s not found in manifest", product)
+	}
+	bytes, err := yaml.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(bytes)), nil
+}
+
+func (provider *HTTPProvider) fetch(uri string) (string, []byte, error) {
+	request, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(provider.Username) > 0 {
+		request.SetBasicAuth(provider.Username, provider.Password)
+	}
+
+	resp, err := provider.HTTPClient.Do(request)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("bad status for url %s: %d", uri, resp.StatusCode)
+	}
+
+	bytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", nil, err
+	}
+
+	ref := strings.Trim(strings.TrimPrefix(resp.Header.Get("ETag"), "W/"), `"`)
+	if len(ref) == 0 {
+		ref = fmt.Sprintf("%x", sha256.Sum256(bytes))
+	}
+
+	return ref, bytes, nil
+}
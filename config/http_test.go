@@ -0,0 +1,167 @@
+package config_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	"github.com/pivotalservices/file-downloader-resource/config"
+	"github.com/pivotalservices/file-downloader-resource/types"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestHTTPProvider(t *testing.T) {
+	spec.Run(t, "HTTPProvider", testHTTPProvider, spec.Report(report.Terminal{}))
+}
+
+func testHTTPProvider(t *testing.T, when spec.G, it spec.S) {
+	var (
+		server   *ghttp.Server
+		provider *config.HTTPProvider
+		manifest string
+	)
+	it.Before(func() {
+		RegisterTestingT(t)
+		server = ghttp.NewServer()
+		manifest = `{"pas": {"version": "2.1.5", "product": "elastic-runtime", "file_pattern": "cf-*.pivotal"}}`
+		provider = config.NewHTTPProvider(server.URL()+"/manifest.json", "", "", "", false)
+	})
+	it.After(func() {
+		server.Close()
+	})
+	when("the server returns an ETag", func() {
+		it("returns the ETag as the version", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/manifest.json"),
+				ghttp.RespondWith(http.StatusOK, manifest, http.Header{"ETag": []string{`W/"abc123"`}}),
+			))
+			version, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(version.Ref).Should(Equal("abc123"))
+		})
+	})
+
+	when("the server does not return an ETag", func() {
+		it("returns a digest of the manifest as the version", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusOK, manifest))
+			version, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(version.Ref).Should(HaveLen(64))
+		})
+	})
+
+	when("getting version info", func() {
+		it("returns the product from the current manifest", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusOK, manifest, http.Header{"ETag": []string{`"abc123"`}}))
+			versionInfo, err := provider.GetVersionInfo("abc123", "pas")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.Version).Should(Equal("2.1.5"))
+			Expect(versionInfo.FilePattern).Should(Equal("cf-*.pivotal"))
+		})
+
+		it("returns an error for an unknown product", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusOK, manifest, http.Header{"ETag": []string{`"abc123"`}}))
+			_, err := provider.GetVersionInfo("abc123", "opsman")
+			Expect(err).Should(HaveOccurred())
+		})
+
+		it("returns an error for a historical revision without a versioned uri", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusOK, manifest, http.Header{"ETag": []string{`"def456"`}}))
+			_, err := provider.GetVersionInfo("abc123", "pas")
+			Expect(err).Should(MatchError(ContainSubstring("no versioned_uri is configured")))
+		})
+
+		it("fetches a historical revision from the versioned uri", func() {
+			provider.VersionedURI = server.URL() + "/manifests/{ref}.yml"
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusOK, manifest, http.Header{"ETag": []string{`"def456"`}}),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/manifests/abc123.yml"),
+					ghttp.RespondWith(http.StatusOK, "pas:\n  version: 2.1.4\n  product: elastic-runtime\n  file_pattern: cf-*.pivotal\n"),
+				),
+			)
+			versionInfo, err := provider.GetVersionInfo("abc123", "pas")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.Version).Should(Equal("2.1.4"))
+		})
+	})
+
+	when("a product is set", func() {
+		var digest string
+		it.Before(func() {
+			provider.Product = "pas"
+			server.AppendHandlers(ghttp.RespondWith(http.StatusOK, manifest, http.Header{"ETag": []string{`"abc123"`}}))
+			version, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(version.Ref).Should(HavePrefix("abc123:"))
+			digest = strings.TrimPrefix(version.Ref, "abc123:")
+			Expect(digest).Should(HaveLen(64))
+		})
+
+		it("keeps the previous version while the entry of the product is unchanged", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusOK, `{"pas": {"file_pattern": "cf-*.pivotal", "product": "elastic-runtime", "version": "2.1.5"}, "opsman": {"version": "2.1.3", "product": "ops-manager", "file_pattern": "*.ova"}}`, http.Header{"ETag": []string{`"def456"`}}))
+			versions, err := provider.VersionsSince(types.Version{Ref: "abc123:" + digest})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versions).Should(Equal([]types.Version{{Ref: "abc123:" + digest}}))
+		})
+
+		it("returns a new version when the entry of the product changes", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusOK, `{"pas": {"version": "2.1.6", "product": "elastic-runtime", "file_pattern": "cf-*.pivotal"}}`, http.Header{"ETag": []string{`"def456"`}}))
+			versions, err := provider.VersionsSince(types.Version{Ref: "abc123:" + digest})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versions).Should(HaveLen(1))
+			Expect(versions[0].Ref).Should(HavePrefix("def456:"))
+			Expect(versions[0].Ref).ShouldNot(HaveSuffix(digest))
+		})
+
+		it("reads the product from the current manifest while its entry is unchanged", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusOK, `{"pas": {"version": "2.1.5", "product": "elastic-runtime", "file_pattern": "cf-*.pivotal"}, "opsman": {"version": "2.1.3", "product": "ops-manager", "file_pattern": "*.ova"}}`, http.Header{"ETag": []string{`"def456"`}}))
+			versionInfo, err := provider.GetVersionInfo("abc123:"+digest, "pas")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.Version).Should(Equal("2.1.5"))
+		})
+
+		it("fetches the manifest of the version from the versioned uri once the entry has changed", func() {
+			provider.VersionedURI = server.URL() + "/manifests/{ref}.json"
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusOK, `{"pas": {"version": "2.1.6", "product": "elastic-runtime", "file_pattern": "cf-*.pivotal"}}`, http.Header{"ETag": []string{`"def456"`}}),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/manifests/abc123.json"),
+					ghttp.RespondWith(http.StatusOK, manifest),
+				),
+			)
+			versionInfo, err := provider.GetVersionInfo("abc123:"+digest, "pas")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.Version).Should(Equal("2.1.5"))
+		})
+	})
+
+	when("products reference variables", func() {
+		it.Before(func() {
+			manifest = "pas:\n  version: ((pas_version))\n  product: elastic-runtime\n  file_pattern: cf-*.pivotal\nopsman:\n  version: ((opsman_version))\n  product: ops-manager\n  file_pattern: \"*((iaas))*\"\n"
+			provider.Vars = map[string]string{"pas_version": "2.1.5"}
+			server.AppendHandlers(ghttp.RespondWith(http.StatusOK, manifest, http.Header{"ETag": []string{`"abc123"`}}))
+		})
+
+		it("only requires the variables of the requested product", func() {
+			versionInfo, err := provider.GetVersionInfo("abc123", "pas")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.Version).Should(Equal("2.1.5"))
+		})
+
+		it("reports the unresolved variables of the requested product", func() {
+			_, err := provider.GetVersionInfo("abc123", "opsman")
+			Expect(err).Should(MatchError(ContainSubstring("opsman in manifest at revision abc123: unresolved variable ((opsman_version))")))
+			Expect(err).Should(MatchError(ContainSubstring("opsman in manifest at revision abc123: unresolved variable ((iaas))")))
+		})
+
+		it("lists products without resolving variables", func() {
+			products, err := provider.ListProducts("abc123")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(products).Should(Equal([]string{"opsman", "pas"}))
+		})
+	})
+}
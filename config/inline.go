@@ -0,0 +1,88 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/pivotalservices/file-downloader-resource/types"
+)
+
+// InlineProvider - reads product configuration from the source itself
+type InlineProvider struct {
+	Products  map[string]types.VersionInfo
+	Documents map[string]interface{}
+	Product   string
+}
+
+// GetVersionInfo - returns the version info for a product if the definitions are still at revision
+func (provider *InlineProvider) GetVersionInfo(revision, productName string) (*types.VersionInfo, error) {
+	current, err := provider.LatestVersion()
+	if err != nil {
+		return nil, err
+	}
+	if len(revision) > 0 && revision != current.Ref {
+		return nil, fmt.Errorf("revision %s does not match the products in source, current revision is %s", revision, current.Ref)
+	}
+
+	versionInfo, ok := provider.Products[productName]
+	if !ok {
+		return nil, fmt.Errorf("product %s not found in source products", productName)
+	}
+
+	err = validateDecoded(fmt.Sprintf("products.%s", productName), provider.Documents[productName], &versionInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &versionInfo, nil
+}
+
+// ListProducts - returns the products defined in source, if the definitions are still at revision
+func (provider *InlineProvider) ListProducts(revision string) ([]string, error) {
+	current, err := provider.LatestVersion()
+	if err != nil {
+		return nil, err
+	}
+	if len(revision) > 0 && revision != current.Ref {
+		return nil, fmt.Errorf("revision %s does not match the products in source, current revision is %s", revision, current.Ref)
+	}
+
+	var names []string
+	for name := range provider.Products {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return onlyProduct(names, provider.Product), nil
+}
+
+// LatestVersion - returns a digest of the product definitions, or of product when it is set
+func (provider *InlineProvider) LatestVersion() (*types.Version, error) {
+	if len(provider.Products) == 0 {
+		return nil, fmt.Errorf("no products defined in source")
+	}
+
+	var definitions interface{} = provider.Products
+	if len(provider.Product) > 0 {
+		versionInfo, ok := provider.Products[provider.Product]
+		if !ok {
+			return nil, fmt.Errorf("product %s not found in source products", provider.Product)
+		}
+		definitions = versionInfo
+	}
+
+	// map keys are marshalled in sorted order, so the digest is stable
+	bytes, err := json.Marshal(definitions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.Version{Ref: fmt.Sprintf("%x", sha256.Sum256(bytes))}, nil
+}
+
+// VersionsSince - returns the latest version only, as earlier product definitions are not retained
+func (provider *InlineProvider) VersionsSince(previous types.Version) ([]types.Version, error) {
+	return latestVersionOnly(provider)
+}
@@ -0,0 +1,116 @@
+package config_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/file-downloader-resource/config"
+	"github.com/pivotalservices/file-downloader-resource/types"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestInlineProvider(t *testing.T) {
+	spec.Run(t, "InlineProvider", testInlineProvider, spec.Report(report.Terminal{}))
+}
+
+func testInlineProvider(t *testing.T, when spec.G, it spec.S) {
+	var provider *config.InlineProvider
+	it.Before(func() {
+		RegisterTestingT(t)
+		provider = &config.InlineProvider{
+			Products: map[string]types.VersionInfo{
+				"pas":    {Version: "2.1.5", PivotalProduct: "elastic-runtime", FilePattern: "cf-*.pivotal"},
+				"opsman": {Version: "2.1.3", PivotalProduct: "ops-manager", FilePattern: "pcf-vsphere-*.ova"},
+			},
+		}
+	})
+	when("products are unchanged", func() {
+		it("returns the same version", func() {
+			originalVersion, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			newVersion, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(newVersion.Ref).Should(Equal(originalVersion.Ref))
+		})
+	})
+
+	when("a product changes", func() {
+		it("returns a new version", func() {
+			originalVersion, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			provider.Products["pas"] = types.VersionInfo{Version: "2.1.6", PivotalProduct: "elastic-runtime", FilePattern: "cf-*.pivotal"}
+			newVersion, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(newVersion.Ref).ShouldNot(Equal(originalVersion.Ref))
+		})
+	})
+
+	when("watching a single product", func() {
+		it("returns the same version when another product changes", func() {
+			provider.Product = "opsman"
+			originalVersion, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			provider.Products["pas"] = types.VersionInfo{Version: "2.1.6", PivotalProduct: "elastic-runtime", FilePattern: "cf-*.pivotal"}
+			newVersion, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(newVersion.Ref).Should(Equal(originalVersion.Ref))
+		})
+	})
+
+	when("listing products", func() {
+		it("returns the products in source", func() {
+			products, err := provider.ListProducts("")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(products).Should(Equal([]string{"opsman", "pas"}))
+		})
+
+		it("returns only the watched product", func() {
+			provider.Product = "opsman"
+			products, err := provider.ListProducts("")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(products).Should(Equal([]string{"opsman"}))
+		})
+	})
+
+	when("getting version info", func() {
+		it("returns the product", func() {
+			version, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			versionInfo, err := provider.GetVersionInfo(version.Ref, "opsman")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.PivotalProduct).Should(Equal("ops-manager"))
+		})
+
+		it("returns an error for an unknown product", func() {
+			version, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+
+			_, err = provider.GetVersionInfo(version.Ref, "srt")
+			Expect(err).Should(HaveOccurred())
+		})
+
+		it("returns an error for unknown keys in the product", func() {
+			var source types.Source
+			err := json.Unmarshal([]byte(`{"config_provider": "inline", "products": {"pas": {"version": "2.1.5", "product": "elastic-runtime", "prodcut": "cf", "file_pattern": "cf-*.pivotal"}}}`), &source)
+			Expect(err).ShouldNot(HaveOccurred())
+			inline, err := config.FromSource(source)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			version, err := inline.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+			_, err = inline.GetVersionInfo(version.Ref, "pas")
+			Expect(err).Should(MatchError(ContainSubstring("products.pas: unknown field prodcut")))
+		})
+
+		it("returns an error for a stale revision", func() {
+			_, err := provider.GetVersionInfo("stale", "pas")
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+}
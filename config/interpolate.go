@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pivotalservices/file-downloader-resource/types"
+)
+
+var variablePattern = regexp.MustCompile(`\(\(\s*([-\w.]+)\s*\)\)`)
+
+// unresolvedVariable - starts the message of the problem for a variable that has no value
+const unresolvedVariable = "unresolved variable"
+
+// interpolate - replaces every ((name)) in the string values of a decoded product with its value from vars,
+// returning a problem, on the line of its key when lines is set, for each variable that has no value.
+// Substituting after decoding means a value can't change the structure of the product file,
+// and variables in comments are never seen
+func interpolate(versionInfo *types.VersionInfo, vars map[string]string, lines *keyLines) []Problem {
+	var problems []Problem
+	interpolateValue(reflect.ValueOf(versionInfo).Elem(), "", func(field, value string) string {
+		for _, match := range variablePattern.FindAllStringSubmatch(value, -1) {
+			if _, ok := vars[match[1]]; !ok {
+				problem := Problem{Message: fmt.Sprintf(unresolvedVariable+" ((%s))", match[1])}
+				if lines != nil {
+					problem.Line = lines.of(field)
+				}
+				problems = append(problems, problem)
+			}
+		}
+		return variablePattern.ReplaceAllStringFunc(value, func(match string) string {
+			return vars[variablePattern.FindStringSubmatch(match)[1]]
+		})
+	})
+
+	return problems
+}
+
+// interpolateValue - calls replace with the dotted field name and value of every string in value,
+// setting the string to what it returns
+func interpolateValue(value reflect.Value, field string, replace func(field, value string) string) {
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(replace(field, value.String()))
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			name := strings.Split(value.Type().Field(i).Tag.Get("json"), ",")[0]
+			if len(name) > 0 && name != "-" {
+				interpolateValue(value.Field(i), joinField(field, name), replace)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			interpolateValue(value.Index(i), joinField(field, fmt.Sprint(i)), replace)
+		}
+	case reflect.Map:
+		var keys []string
+		for _, key := range value.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			element := reflect.New(value.Type().Elem()).Elem()
+			element.Set(value.MapIndex(reflect.ValueOf(key)))
+			interpolateValue(element, joinField(field, key), replace)
+			value.SetMapIndex(reflect.ValueOf(key), element)
+		}
+	}
+}
+
+func joinField(field, name string) string {
+	if len(field) == 0 {
+		return name
+	}
+	return field + "." + name
+}
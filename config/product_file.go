@@ -0,0 +1,104 @@
+package config
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// productFileExtensions - supported product file formats, in discovery order
+var productFileExtensions = []string{".yml", ".yaml", ".json", ".toml"}
+
+// productFileNames - returns the candidate file names for a product, in discovery order
+func productFileNames(productName string) []string {
+	var names []string
+	for _, extension := range productFileExtensions {
+		names = append(names, productName+extension)
+	}
+
+	return names
+}
+
+// isProductFileName - reports whether name has a supported product file extension
+func isProductFileName(name string) bool {
+	extension := path.Ext(name)
+	for _, supported := range productFileExtensions {
+		if extension == supported {
+			return true
+		}
+	}
+
+	return false
+}
+
+// productNameOf - returns the product name of a product file path
+func productNameOf(name string) string {
+	base := path.Base(name)
+	return strings.TrimSuffix(base, path.Ext(base))
+}
+
+// versionRoots - returns version_root followed by the overlay roots that are layered over it
+func versionRoots(versionRoot string, overlayRoots []string) []string {
+	return append([]string{versionRoot}, overlayRoots...)
+}
+
+// inRoots - reports whether file is directly under one of roots
+func inRoots(file string, roots []string) bool {
+	for _, root := range roots {
+		if path.Dir(file) == path.Clean(root) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// productNamesOf - returns the sorted, distinct names of the products with a file directly under one of roots,
+// leaving out those starting with _, such as _defaults, that are only used by other products
+func productNamesOf(files []string, roots []string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, file := range files {
+		name := productNameOf(file)
+		if !isProductFileName(file) || !inRoots(file, roots) || strings.HasPrefix(name, "_") || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// onlyProduct - narrows names down to product when it is set
+func onlyProduct(names []string, product string) []string {
+	if len(product) == 0 {
+		return names
+	}
+	for _, name := range names {
+		if name == product {
+			return []string{name}
+		}
+	}
+
+	return nil
+}
+
+func displayRoot(root string) string {
+	if len(root) == 0 {
+		return "."
+	}
+
+	return root
+}
+
+func containsString(names []string, name string) bool {
+	for _, candidate := range names {
+		if candidate == name {
+			return true
+		}
+	}
+
+	return false
+}
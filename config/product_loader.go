@@ -0,0 +1,224 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/pivotalservices/file-downloader-resource/types"
+)
+
+// defaultsProductName - the product file in version_root whose fields every product inherits
+const defaultsProductName = "_defaults"
+
+// maxExtendsDepth - guards against runaway extends chains
+const maxExtendsDepth = 16
+
+// productFiles - loads products from the files under roots of one provider revision,
+// where files in later roots are layered over those in earlier ones
+type productFiles struct {
+	roots    []string
+	location string
+	vars     map[string]string
+	exists   func(name string) (bool, error)
+	read     func(name string) ([]byte, error)
+}
+
+// productLayer - a decoded product file, the lines its keys are on and any unknown keys it has
+type productLayer struct {
+	name        string
+	versionInfo *types.VersionInfo
+	lines       *keyLines
+	problems    []Problem
+}
+
+// load - returns productName merged over the products it extends and _defaults, with its provenance
+func (files *productFiles) load(productName string) (*types.VersionInfo, error) {
+	var (
+		layers []*productLayer
+		chain  []string
+	)
+	for name := productName; len(name) > 0; {
+		for _, seen := range chain {
+			if seen == name {
+				return nil, fmt.Errorf("product %s extends itself: %s -> %s", productName, strings.Join(chain, " -> "), name)
+			}
+		}
+		chain = append(chain, name)
+		if len(chain) > maxExtendsDepth {
+			return nil, fmt.Errorf("product %s extends more than %d products: %s", productName, maxExtendsDepth, strings.Join(chain, " -> "))
+		}
+
+		productLayers, err := files.layers(name)
+		if err != nil {
+			return nil, err
+		}
+		if len(productLayers) == 0 {
+			return nil, fmt.Errorf("no configuration file for product %s found in %s%s, tried %s", name, files.displayRoots(), files.location, strings.Join(productFileNames(name), ", "))
+		}
+		layers = append(productLayers, layers...)
+
+		name = ""
+		for _, layer := range productLayers {
+			if len(layer.versionInfo.Extends) > 0 {
+				name = layer.versionInfo.Extends
+			}
+		}
+	}
+
+	if productName != defaultsProductName {
+		defaults, err := files.layers(defaultsProductName)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(defaults, layers...)
+	}
+
+	return mergeLayers(layers)
+}
+
+// chain - returns productName, the products it extends and _defaults, which are the products whose files
+// it is merged from, following extends for as long as the files can be read
+func (files *productFiles) chain(productName string) []string {
+	var chain []string
+	for name := productName; len(name) > 0 && !containsString(chain, name) && len(chain) <= maxExtendsDepth; {
+		chain = append(chain, name)
+		layers, err := files.layers(name)
+		name = ""
+		if err != nil {
+			break
+		}
+		for _, layer := range layers {
+			if len(layer.versionInfo.Extends) > 0 {
+				name = layer.versionInfo.Extends
+			}
+		}
+	}
+	if !containsString(chain, defaultsProductName) {
+		chain = append(chain, defaultsProductName)
+	}
+
+	return chain
+}
+
+// layers - reads and decodes the files for productName in each of the roots that has one
+func (files *productFiles) layers(productName string) ([]*productLayer, error) {
+	var layers []*productLayer
+	for _, root := range files.roots {
+		found, err := files.find(root, productName)
+		if err != nil {
+			return nil, err
+		}
+		if len(found) == 0 {
+			continue
+		}
+
+		contents, err := files.read(found)
+		if err != nil {
+			return nil, err
+		}
+
+		layer, err := decodeProductFile(found, contents, files.vars)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, layer)
+	}
+
+	return layers, nil
+}
+
+// find - returns the file for productName under root, or nothing when there is none,
+// and an error when there is more than one
+func (files *productFiles) find(root, productName string) (string, error) {
+	var found []string
+	for _, name := range productFileNames(productName) {
+		ok, err := files.exists(path.Join(root, name))
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			found = append(found, name)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return "", nil
+	case 1:
+		return path.Join(root, found[0]), nil
+	default:
+		return "", fmt.Errorf("more than one configuration file for product %s found in %s%s: %s", productName, displayRoot(root), files.location, strings.Join(found, ", "))
+	}
+}
+
+func (files *productFiles) displayRoots() string {
+	var roots []string
+	for _, root := range files.roots {
+		roots = append(roots, displayRoot(root))
+	}
+
+	return strings.Join(roots, ", ")
+}
+
+// mergeLayers - merges layers in order, each overriding the fields set by those before it,
+// and validates the result
+func mergeLayers(layers []*productLayer) (*types.VersionInfo, error) {
+	product := layers[len(layers)-1].name
+	merged := &types.VersionInfo{}
+	provenance := &types.Provenance{Fields: map[string]string{}}
+	lines := map[string]*keyLines{}
+	var problems []Problem
+	for _, layer := range layers {
+		for _, field := range mergeVersionInfo(merged, layer.versionInfo) {
+			provenance.Fields[field] = layer.name
+		}
+		provenance.Files = append(provenance.Files, layer.name)
+		lines[layer.name] = layer.lines
+		for _, problem := range layer.problems {
+			if layer.name != product {
+				problem.File = layer.name
+			}
+			problems = append(problems, problem)
+		}
+	}
+
+	for _, problem := range validateVersionInfo(merged) {
+		file, ok := provenance.Fields[strings.Split(problem.field, ".")[0]]
+		if ok {
+			problem.Line = lines[file].of(problem.field)
+		}
+		if ok && file != product {
+			problem.File = file
+		}
+		problems = append(problems, problem.Problem)
+	}
+	if len(problems) > 0 {
+		return nil, &ProductFileError{File: product, Problems: problems}
+	}
+	merged.Provenance = provenance
+
+	return merged, nil
+}
+
+// mergeVersionInfo - copies the fields other than extends that are set in layer over merged, returning their names
+func mergeVersionInfo(merged, layer *types.VersionInfo) []string {
+	var fields []string
+	target := reflect.ValueOf(merged).Elem()
+	source := reflect.ValueOf(layer).Elem()
+	for i := 0; i < source.NumField(); i++ {
+		name := strings.Split(source.Type().Field(i).Tag.Get("json"), ",")[0]
+		if len(name) == 0 || name == "-" || name == "extends" {
+			continue
+		}
+		value := source.Field(i)
+		if reflect.DeepEqual(value.Interface(), reflect.Zero(value.Type()).Interface()) {
+			continue
+		}
+		target.Field(i).Set(value)
+		fields = append(fields, name)
+	}
+
+	return fields
+}
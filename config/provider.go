@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 
+	"github.com/pivotalservices/file-downloader-resource/file"
 	"github.com/pivotalservices/file-downloader-resource/types"
 )
 
@@ -11,9 +12,15 @@ import (
 // Provider - defines the interface for how to fetch configuration
 type Provider interface {
 	LatestVersion() (*types.Version, error)
+	VersionsSince(previous types.Version) ([]types.Version, error)
 	GetVersionInfo(revision, productName string) (*types.VersionInfo, error)
 }
 
+// ProductLister - implemented by providers that can list the products they have at a revision
+type ProductLister interface {
+	ListProducts(revision string) ([]string, error)
+}
+
 // FromSource - factory to return appropriate driver based on configuration
 func FromSource(source types.Source) (Provider, error) {
 
@@ -21,16 +28,79 @@ func FromSource(source types.Source) (Provider, error) {
 	case types.ConfigProviderUnspecified, types.ConfigProviderGit:
 
 		return &GitProvider{
-			VersionRoot: source.VersionRoot,
-			URI:         source.URI,
-			Branch:      source.Branch,
-			PrivateKey:  source.PrivateKey,
-			Username:    source.Username,
-			Password:    source.Password,
-			Path:        source.Path,
+			VersionRoot:            source.VersionRoot,
+			OverlayRoots:           source.OverlayRoots,
+			URI:                    source.URI,
+			Branch:                 source.Branch,
+			PrivateKey:             source.PrivateKey,
+			PrivateKeyPassphrase:   source.PrivateKeyPassphrase,
+			KnownHosts:             source.KnownHosts,
+			HostKeyFingerprint:     source.HostKeyFingerprint,
+			Username:               source.Username,
+			Password:               source.Password,
+			AccessToken:            source.AccessToken,
+			Path:                   source.Path,
+			Paths:                  source.Paths,
+			IgnorePaths:            source.IgnorePaths,
+			TagFilter:              source.TagFilter,
+			CommitVerificationKeys: source.CommitVerificationKeys,
+			Product:                source.Product,
+			Vars:                   source.Vars,
+		}, nil
+
+	case types.ConfigProviderDir:
+
+		return &DirProvider{
+			VersionRoot:  source.VersionRoot,
+			OverlayRoots: source.OverlayRoots,
+			Product:      source.Product,
+			Vars:         source.Vars,
+		}, nil
+
+	case types.ConfigProviderS3:
+
+		return &S3Provider{
+			Client:       file.NewS3Client(source.AccessKeyID, source.SecretAccessKey, source.RegionName, source.Endpoint, source.SkipSSLVerification, source.DisableSSL, source.UseV2Signing),
+			BucketName:   source.Bucket,
+			VersionRoot:  source.VersionRoot,
+			OverlayRoots: source.OverlayRoots,
+			Product:      source.Product,
+			Vars:         source.Vars,
+		}, nil
+
+	case types.ConfigProviderHTTP:
+		if len(source.OverlayRoots) > 0 {
+			return nil, fmt.Errorf("overlay_roots is not supported by the %s config provider", source.ConfigProvider)
+		}
+
+		provider := NewHTTPProvider(source.URI, source.VersionedURI, source.Username, source.Password, source.SkipSSLVerification)
+		provider.Product = source.Product
+		provider.Vars = source.Vars
+
+		return provider, nil
+
+	case types.ConfigProviderInline:
+		if len(source.OverlayRoots) > 0 {
+			return nil, fmt.Errorf("overlay_roots is not supported by the %s config provider", source.ConfigProvider)
+		}
+
+		return &InlineProvider{
+			Products:  source.Products,
+			Documents: source.ProductDocuments,
+			Product:   source.Product,
 		}, nil
 
 	default:
 		return nil, fmt.Errorf("unknown provider: %s", source.ConfigProvider)
 	}
 }
+
+// latestVersionOnly - for providers that can't list the versions between previous and the latest
+func latestVersionOnly(provider Provider) ([]types.Version, error) {
+	version, err := provider.LatestVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	return []types.Version{*version}, nil
+}
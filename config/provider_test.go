@@ -0,0 +1,48 @@
+package config_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/file-downloader-resource/config"
+	"github.com/pivotalservices/file-downloader-resource/types"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestFromSource(t *testing.T) {
+	spec.Run(t, "FromSource", testFromSource, spec.Report(report.Terminal{}))
+}
+
+func testFromSource(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	when("the config provider does not support a setting", func() {
+		it("rejects overlay_roots for the http provider", func() {
+			_, err := config.FromSource(types.Source{ConfigProvider: types.ConfigProviderHTTP, URI: "https://example.com/manifest.yml", OverlayRoots: []string{"prod"}})
+			Expect(err).Should(MatchError("overlay_roots is not supported by the http config provider"))
+		})
+
+		it("rejects overlay_roots for the inline provider", func() {
+			_, err := config.FromSource(types.Source{ConfigProvider: types.ConfigProviderInline, OverlayRoots: []string{"prod"}})
+			Expect(err).Should(MatchError("overlay_roots is not supported by the inline config provider"))
+		})
+	})
+
+	when("the config provider is http", func() {
+		it("versions the product of source", func() {
+			provider, err := config.FromSource(types.Source{ConfigProvider: types.ConfigProviderHTTP, URI: "https://example.com/manifest.yml", Product: "pas"})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(provider.(*config.HTTPProvider).Product).Should(Equal("pas"))
+		})
+	})
+
+	when("the config provider supports overlay_roots", func() {
+		it("accepts them", func() {
+			_, err := config.FromSource(types.Source{ConfigProvider: types.ConfigProviderDir, VersionRoot: "versions", OverlayRoots: []string{"versions/prod"}})
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+	})
+}
@@ -0,0 +1,317 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+
+	"github.com/pivotalservices/file-downloader-resource/types"
+)
+
+// S3Provider - reads product configuration from objects under a bucket prefix
+//
+// The version ref is the time of the most recent change to any object under
+// the prefix, followed by the version ID of each product file at that time, so
+// that a ref always reads the same objects, even when an upload that started
+// before the time completes after the ref was reported.
+type S3Provider struct {
+	Client       s3iface.S3API
+	BucketName   string
+	VersionRoot  string
+	OverlayRoots []string
+	Product      string
+	Vars         map[string]string
+}
+
+type objectVersion struct {
+	key          string
+	versionID    string
+	lastModified time.Time
+	deleted      bool
+}
+
+// GetVersionInfo - returns the version info for a product as it was at revision
+func (provider *S3Provider) GetVersionInfo(revision, productName string) (*types.VersionInfo, error) {
+	current, err := provider.objectsAt(revision)
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.productFilesAt(current, fmt.Sprintf(" in bucket %s at revision %s", provider.BucketName, revision)).load(productName)
+}
+
+// productFilesAt - returns the product files in current, the version of each object at a revision
+func (provider *S3Provider) productFilesAt(current map[string]*objectVersion, location string) *productFiles {
+	return &productFiles{
+		roots:    versionRoots(provider.VersionRoot, provider.OverlayRoots),
+		location: location,
+		vars:     provider.Vars,
+		exists: func(key string) (bool, error) {
+			match, ok := current[key]
+			return ok && !match.deleted, nil
+		},
+		read: func(key string) ([]byte, error) {
+			return provider.getObject(key, current[key].versionID)
+		},
+	}
+}
+
+// ListProducts - returns the products with a file under version_root or an overlay root at revision
+func (provider *S3Provider) ListProducts(revision string) ([]string, error) {
+	current, err := provider.objectsAt(revision)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for key, version := range current {
+		if !version.deleted {
+			keys = append(keys, key)
+		}
+	}
+
+	return onlyProduct(productNamesOf(keys, versionRoots(provider.VersionRoot, provider.OverlayRoots)), provider.Product), nil
+}
+
+// objectsAt - returns the version of each object at revision, from the version IDs it records, or for a
+// revision that only records a time, the version that was current at that time
+func (provider *S3Provider) objectsAt(revision string) (map[string]*objectVersion, error) {
+	revisionTime, versionIDs, err := parseRef(revision)
+	if err != nil {
+		return nil, err
+	}
+	if versionIDs != nil {
+		current := map[string]*objectVersion{}
+		for key := range versionIDs {
+			current[key] = &objectVersion{key: key, versionID: versionIDs.Get(key), lastModified: revisionTime}
+		}
+		return current, nil
+	}
+
+	versions, err := provider.objectVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	return currentAt(versions, revisionTime), nil
+}
+
+// refAt - returns the ref of the objects in versions that were current at revisionTime
+func refAt(versions []objectVersion, revisionTime time.Time) string {
+	versionIDs := url.Values{}
+	for key, version := range currentAt(versions, revisionTime) {
+		if !version.deleted && isProductFileName(key) {
+			versionIDs.Set(key, version.versionID)
+		}
+	}
+
+	return revisionTime.UTC().Format(time.RFC3339Nano) + "?" + versionIDs.Encode()
+}
+
+// parseRef - returns the time of ref and the version ID of each product file it records, which are nil for
+// a ref that only records a time
+func parseRef(ref string) (time.Time, url.Values, error) {
+	parts := strings.SplitN(ref, "?", 2)
+	revisionTime, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("invalid revision %s: %s", ref, err)
+	}
+	if len(parts) == 1 {
+		return revisionTime, nil, nil
+	}
+	versionIDs, err := url.ParseQuery(parts[1])
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("invalid revision %s: %s", ref, err)
+	}
+
+	return revisionTime, versionIDs, nil
+}
+
+// currentAt - returns the version of each object in versions that was current at revisionTime
+func currentAt(versions []objectVersion, revisionTime time.Time) map[string]*objectVersion {
+	current := map[string]*objectVersion{}
+	for i, version := range versions {
+		if version.lastModified.After(revisionTime) {
+			continue
+		}
+		if match, ok := current[version.key]; !ok || version.lastModified.After(match.lastModified) {
+			current[version.key] = &versions[i]
+		}
+	}
+
+	return current
+}
+
+// LatestVersion - returns the time of the most recent change under the prefix
+func (provider *S3Provider) LatestVersion() (*types.Version, error) {
+	versions, err := provider.objectVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	var latest time.Time
+	for _, version := range versions {
+		if version.lastModified.After(latest) && provider.changesProduct(versions, version) {
+			latest = version.lastModified
+		}
+	}
+	if latest.IsZero() {
+		return nil, fmt.Errorf("no product files found in bucket %s, folder %s", provider.BucketName, provider.VersionRoot)
+	}
+
+	return &types.Version{Ref: refAt(versions, latest)}, nil
+}
+
+// VersionsSince - returns every change under the prefix since previous, including objects whose upload
+// started before previous but completed after it was reported
+func (provider *S3Provider) VersionsSince(previous types.Version) ([]types.Version, error) {
+	previousTime, previousIDs, err := parseRef(previous.Ref)
+	if err != nil {
+		return latestVersionOnly(provider)
+	}
+
+	versions, err := provider.objectVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[time.Time]bool{}
+	for _, version := range versions {
+		if version.lastModified.After(previousTime) && !changes[version.lastModified.UTC()] && provider.changesProduct(versions, version) {
+			changes[version.lastModified.UTC()] = true
+		}
+	}
+
+	var changeTimes []time.Time
+	for changeTime := range changes {
+		changeTimes = append(changeTimes, changeTime)
+	}
+	sort.Slice(changeTimes, func(i, j int) bool {
+		return changeTimes[i].Before(changeTimes[j])
+	})
+
+	result := []types.Version{previous}
+	if previousIDs != nil && provider.completedSince(versions, previousTime, previousIDs) {
+		result = append(result, types.Version{Ref: refAt(versions, previousTime)})
+	}
+	for _, changeTime := range changeTimes {
+		result = append(result, types.Version{Ref: refAt(versions, changeTime)})
+	}
+
+	return result, nil
+}
+
+// completedSince - reports whether a product file that was current at previousTime now has a different version
+// than previousIDs records, because its upload completed after the previous version was reported
+func (provider *S3Provider) completedSince(versions []objectVersion, previousTime time.Time, previousIDs url.Values) bool {
+	_, currentIDs, _ := parseRef(refAt(versions, previousTime))
+	keys := map[string]bool{}
+	for key := range previousIDs {
+		keys[key] = true
+	}
+	for key := range currentIDs {
+		keys[key] = true
+	}
+	for key := range keys {
+		if previousIDs.Get(key) != currentIDs.Get(key) && provider.changesProduct(versions, objectVersion{key: key, lastModified: previousTime}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (provider *S3Provider) getObject(key, versionID string) ([]byte, error) {
+	getObject := &s3.GetObjectInput{
+		Bucket: aws.String(provider.BucketName),
+		Key:    aws.String(key),
+	}
+	if len(versionID) > 0 {
+		getObject.VersionId = aws.String(versionID)
+	}
+	object, err := provider.Client.GetObject(getObject)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Body.Close()
+
+	return ioutil.ReadAll(object.Body)
+}
+
+// changesProduct - reports whether version is of a product file, and when product is set, of one of the files
+// product was merged from at the time of the change
+func (provider *S3Provider) changesProduct(versions []objectVersion, version objectVersion) bool {
+	if !isProductFileName(version.key) {
+		return false
+	}
+	if len(provider.Product) == 0 {
+		return true
+	}
+	if !inRoots(version.key, versionRoots(provider.VersionRoot, provider.OverlayRoots)) {
+		return false
+	}
+
+	chain := provider.productFilesAt(currentAt(versions, version.lastModified), "").chain(provider.Product)
+	return containsString(chain, productNameOf(version.key))
+}
+
+// rootPrefix - the key prefix of the objects under root, which ends in / so that a sibling such as
+// versions-old is not listed along with versions
+func rootPrefix(root string) string {
+	root = strings.Trim(root, "/")
+	if len(root) == 0 {
+		return ""
+	}
+
+	return root + "/"
+}
+
+// objectVersions - lists the object versions under version_root and the overlay roots
+func (provider *S3Provider) objectVersions() ([]objectVersion, error) {
+	var versions []objectVersion
+	seen := map[objectVersion]bool{}
+
+	for _, root := range versionRoots(provider.VersionRoot, provider.OverlayRoots) {
+		err := provider.Client.ListObjectVersionsPages(&s3.ListObjectVersionsInput{
+			Bucket: aws.String(provider.BucketName),
+			Prefix: aws.String(rootPrefix(root)),
+		}, func(page *s3.ListObjectVersionsOutput, lastPage bool) bool {
+			var pageVersions []objectVersion
+			for _, version := range page.Versions {
+				pageVersions = append(pageVersions, objectVersion{
+					key:          aws.StringValue(version.Key),
+					versionID:    aws.StringValue(version.VersionId),
+					lastModified: aws.TimeValue(version.LastModified),
+				})
+			}
+			for _, marker := range page.DeleteMarkers {
+				pageVersions = append(pageVersions, objectVersion{
+					key:          aws.StringValue(marker.Key),
+					versionID:    aws.StringValue(marker.VersionId),
+					lastModified: aws.TimeValue(marker.LastModified),
+					deleted:      true,
+				})
+			}
+			// roots nested in another root are listed more than once
+			for _, version := range pageVersions {
+				if !seen[version] {
+					seen[version] = true
+					versions = append(versions, version)
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return versions, nil
+}
@@ -0,0 +1,240 @@
+package config_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/file-downloader-resource/config"
+	"github.com/pivotalservices/file-downloader-resource/types"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+type fakeS3Client struct {
+	s3iface.S3API
+	versions      []*s3.ObjectVersion
+	deleteMarkers []*s3.DeleteMarkerEntry
+	objects       map[string]string
+}
+
+func (c *fakeS3Client) ListObjectVersionsPages(input *s3.ListObjectVersionsInput, fn func(*s3.ListObjectVersionsOutput, bool) bool) error {
+	output := &s3.ListObjectVersionsOutput{}
+	for _, version := range c.versions {
+		if strings.HasPrefix(aws.StringValue(version.Key), aws.StringValue(input.Prefix)) {
+			output.Versions = append(output.Versions, version)
+		}
+	}
+	for _, marker := range c.deleteMarkers {
+		if strings.HasPrefix(aws.StringValue(marker.Key), aws.StringValue(input.Prefix)) {
+			output.DeleteMarkers = append(output.DeleteMarkers, marker)
+		}
+	}
+	fn(output, true)
+	return nil
+}
+
+func (c *fakeS3Client) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	return &s3.GetObjectOutput{
+		Body: ioutil.NopCloser(bytes.NewBufferString(c.objects[aws.StringValue(input.VersionId)])),
+	}, nil
+}
+
+func TestS3Provider(t *testing.T) {
+	spec.Run(t, "S3Provider", testS3Provider, spec.Report(report.Terminal{}))
+}
+
+func testS3Provider(t *testing.T, when spec.G, it spec.S) {
+	var (
+		client   *fakeS3Client
+		provider *config.S3Provider
+		first    time.Time
+		second   time.Time
+	)
+	it.Before(func() {
+		RegisterTestingT(t)
+		first = time.Date(2018, 9, 1, 10, 0, 0, 0, time.UTC)
+		second = first.Add(time.Hour)
+		client = &fakeS3Client{
+			versions: []*s3.ObjectVersion{
+				{Key: aws.String("versions/pas.yml"), VersionId: aws.String("v1"), LastModified: aws.Time(first)},
+				{Key: aws.String("versions/pas.yml"), VersionId: aws.String("v2"), LastModified: aws.Time(second)},
+			},
+			objects: map[string]string{
+				"v1": "version: 2.1.5\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\n",
+				"v2": "version: 2.1.6\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\n",
+			},
+		}
+		provider = &config.S3Provider{
+			Client:      client,
+			BucketName:  "bucket",
+			VersionRoot: "versions",
+		}
+	})
+	when("getting the latest version", func() {
+		it("returns the time of the newest change and the version of each product file", func() {
+			version, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(version.Ref).Should(Equal("2018-09-01T11:00:00Z?versions%2Fpas.yml=v2"))
+		})
+
+		it("ignores objects in folders that only share the prefix of version_root", func() {
+			client.versions = append(client.versions, &s3.ObjectVersion{Key: aws.String("versions-old/pas.yml"), VersionId: aws.String("v4"), LastModified: aws.Time(second.Add(time.Hour))})
+			version, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(version.Ref).Should(Equal("2018-09-01T11:00:00Z?versions%2Fpas.yml=v2"))
+		})
+
+		it("includes deletions", func() {
+			client.deleteMarkers = []*s3.DeleteMarkerEntry{
+				{Key: aws.String("versions/pas.yml"), VersionId: aws.String("v3"), LastModified: aws.Time(second.Add(time.Hour))},
+			}
+			version, err := provider.LatestVersion()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(version.Ref).Should(Equal("2018-09-01T12:00:00Z?"))
+		})
+	})
+
+	when("checking for versions since a previous version", func() {
+		it("returns every change since the previous version in order", func() {
+			client.versions = append(client.versions, &s3.ObjectVersion{Key: aws.String("versions/opsman.yml"), VersionId: aws.String("v4"), LastModified: aws.Time(second.Add(time.Hour))})
+			versions, err := provider.VersionsSince(types.Version{Ref: "2018-09-01T10:00:00Z"})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versions).Should(Equal([]types.Version{
+				{Ref: "2018-09-01T10:00:00Z"},
+				{Ref: "2018-09-01T11:00:00Z?versions%2Fpas.yml=v2"},
+				{Ref: "2018-09-01T12:00:00Z?versions%2Fopsman.yml=v4&versions%2Fpas.yml=v2"},
+			}))
+		})
+
+		it("only returns changes to product when it is set", func() {
+			provider.Product = "pas"
+			client.versions = append(client.versions, &s3.ObjectVersion{Key: aws.String("versions/opsman.yml"), VersionId: aws.String("v4"), LastModified: aws.Time(second.Add(time.Hour))})
+			versions, err := provider.VersionsSince(types.Version{Ref: "2018-09-01T10:00:00Z"})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versions).Should(Equal([]types.Version{
+				{Ref: "2018-09-01T10:00:00Z"},
+				{Ref: "2018-09-01T11:00:00Z?versions%2Fpas.yml=v2"},
+			}))
+		})
+
+		it("returns changes to the files product extends when it is set", func() {
+			provider.Product = "pas"
+			third := second.Add(time.Hour)
+			client.versions = append(client.versions,
+				&s3.ObjectVersion{Key: aws.String("versions/pas.yml"), VersionId: aws.String("v3"), LastModified: aws.Time(third)},
+				&s3.ObjectVersion{Key: aws.String("versions/pas-base.yml"), VersionId: aws.String("v4"), LastModified: aws.Time(third.Add(time.Hour))},
+				&s3.ObjectVersion{Key: aws.String("versions/opsman-base.yml"), VersionId: aws.String("v5"), LastModified: aws.Time(third.Add(2 * time.Hour))},
+			)
+			client.objects["v3"] = "extends: pas-base\nversion: 2.1.6\n"
+			client.objects["v4"] = "product: elastic-runtime\nfile_pattern: cf-*.pivotal\n"
+			client.objects["v5"] = "product: ops-manager\n"
+			versions, err := provider.VersionsSince(types.Version{Ref: "2018-09-01T11:00:00Z"})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versions).Should(Equal([]types.Version{
+				{Ref: "2018-09-01T11:00:00Z"},
+				{Ref: "2018-09-01T12:00:00Z?versions%2Fpas.yml=v3"},
+				{Ref: "2018-09-01T13:00:00Z?versions%2Fpas-base.yml=v4&versions%2Fpas.yml=v3"},
+			}))
+		})
+
+		it("returns the latest version when there is no previous version", func() {
+			versions, err := provider.VersionsSince(types.Version{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versions).Should(Equal([]types.Version{{Ref: "2018-09-01T11:00:00Z?versions%2Fpas.yml=v2"}}))
+		})
+
+		it("returns the objects of the previous version again when an upload completed after it was reported", func() {
+			client.versions = append(client.versions, &s3.ObjectVersion{Key: aws.String("versions/opsman.yml"), VersionId: aws.String("v4"), LastModified: aws.Time(second.Add(-time.Minute))})
+			versions, err := provider.VersionsSince(types.Version{Ref: "2018-09-01T11:00:00Z?versions%2Fpas.yml=v2"})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versions).Should(Equal([]types.Version{
+				{Ref: "2018-09-01T11:00:00Z?versions%2Fpas.yml=v2"},
+				{Ref: "2018-09-01T11:00:00Z?versions%2Fopsman.yml=v4&versions%2Fpas.yml=v2"},
+			}))
+		})
+
+		it("ignores late uploads of other products when product is set", func() {
+			provider.Product = "pas"
+			client.versions = append(client.versions, &s3.ObjectVersion{Key: aws.String("versions/opsman.yml"), VersionId: aws.String("v4"), LastModified: aws.Time(second.Add(-time.Minute))})
+			versions, err := provider.VersionsSince(types.Version{Ref: "2018-09-01T11:00:00Z?versions%2Fpas.yml=v2"})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versions).Should(Equal([]types.Version{{Ref: "2018-09-01T11:00:00Z?versions%2Fpas.yml=v2"}}))
+		})
+	})
+
+	when("listing products", func() {
+		it("returns the products that existed at the revision", func() {
+			client.versions = append(client.versions,
+				&s3.ObjectVersion{Key: aws.String("versions/opsman.yml"), VersionId: aws.String("v4"), LastModified: aws.Time(second)},
+				&s3.ObjectVersion{Key: aws.String("versions/_defaults.yml"), VersionId: aws.String("v5"), LastModified: aws.Time(first)},
+			)
+			client.deleteMarkers = []*s3.DeleteMarkerEntry{
+				{Key: aws.String("versions/opsman.yml"), VersionId: aws.String("v6"), LastModified: aws.Time(second.Add(time.Hour))},
+			}
+
+			products, err := provider.ListProducts("2018-09-01T10:30:00Z")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(products).Should(Equal([]string{"pas"}))
+
+			products, err = provider.ListProducts("2018-09-01T11:00:00Z")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(products).Should(Equal([]string{"opsman", "pas"}))
+
+			products, err = provider.ListProducts("2018-09-01T12:00:00Z")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(products).Should(Equal([]string{"pas"}))
+		})
+	})
+
+	when("getting version info", func() {
+		it("returns the object version current at the revision", func() {
+			versionInfo, err := provider.GetVersionInfo("2018-09-01T10:30:00Z", "pas")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.Version).Should(Equal("2.1.5"))
+
+			versionInfo, err = provider.GetVersionInfo("2018-09-01T11:00:00Z", "pas")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.Version).Should(Equal("2.1.6"))
+		})
+
+		it("layers the overlay roots over version_root", func() {
+			provider.OverlayRoots = []string{"versions/prod"}
+			client.versions = append(client.versions, &s3.ObjectVersion{Key: aws.String("versions/prod/pas.yml"), VersionId: aws.String("v4"), LastModified: aws.Time(second)})
+			client.objects["v4"] = "version: 2.1.7\n"
+
+			versionInfo, err := provider.GetVersionInfo("2018-09-01T11:00:00Z", "pas")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.Version).Should(Equal("2.1.7"))
+			Expect(versionInfo.PivotalProduct).Should(Equal("elastic-runtime"))
+			Expect(versionInfo.Provenance.Files).Should(Equal([]string{"versions/pas.yml", "versions/prod/pas.yml"}))
+			Expect(versionInfo.Provenance.Fields["version"]).Should(Equal("versions/prod/pas.yml"))
+			Expect(versionInfo.Provenance.Fields["product"]).Should(Equal("versions/pas.yml"))
+		})
+
+		it("reads the object versions the revision records", func() {
+			client.versions = append(client.versions, &s3.ObjectVersion{Key: aws.String("versions/pas.yml"), VersionId: aws.String("v3"), LastModified: aws.Time(first.Add(time.Minute))})
+			client.objects["v3"] = "version: 2.1.4\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\n"
+
+			versionInfo, err := provider.GetVersionInfo("2018-09-01T10:30:00Z?versions%2Fpas.yml=v1", "pas")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.Version).Should(Equal("2.1.5"))
+		})
+
+		it("returns an error when the product did not exist at the revision", func() {
+			_, err := provider.GetVersionInfo("2018-09-01T09:00:00Z", "pas")
+			Expect(err).Should(HaveOccurred())
+		})
+
+		it("returns an error for an invalid revision", func() {
+			_, err := provider.GetVersionInfo("a2b5630e85d4a72280fd825da5fddad7398aa8e3", "pas")
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+}
@@ -0,0 +1,378 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/Masterminds/semver"
+	"gopkg.in/yaml.v2"
+
+	"github.com/pivotalservices/file-downloader-resource/file"
+	"github.com/pivotalservices/file-downloader-resource/types"
+)
+
+// Problem - a single problem found in a product file, Line is 0 when it can't be tied to a line
+// and File is only set when the problem is in a file the product inherits from
+type Problem struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// ProductFileError - returned with every problem found in a product file
+type ProductFileError struct {
+	File     string    `json:"file"`
+	Problems []Problem `json:"problems"`
+}
+
+func (e *ProductFileError) Error() string {
+	lines := []string{fmt.Sprintf("invalid product file %s:", e.File)}
+	for _, problem := range e.Problems {
+		file := e.File
+		if len(problem.File) > 0 {
+			file = problem.File
+		}
+		if problem.Line > 0 {
+			lines = append(lines, fmt.Sprintf("  %s:%d: %s", file, problem.Line, problem.Message))
+		} else {
+			lines = append(lines, fmt.Sprintf("  %s: %s", file, problem.Message))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// OnlyUnresolvedVariables - whether every problem is a variable without a value, which the vars of a get
+// step may still supply
+func (e *ProductFileError) OnlyUnresolvedVariables() bool {
+	for _, problem := range e.Problems {
+		if !strings.HasPrefix(problem.Message, unresolvedVariable+" ") {
+			return false
+		}
+	}
+
+	return len(e.Problems) > 0
+}
+
+// ValidateProductFile - strictly decodes the product file name, interpolates vars into it and validates it
+// on its own, returning all problems at once
+func ValidateProductFile(name string, contents []byte, vars map[string]string) (*types.VersionInfo, error) {
+	layer, err := decodeProductFile(name, contents, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeLayers([]*productLayer{layer})
+}
+
+// decodeProductFile - strictly decodes the product file name and interpolates vars into it, recording unknown
+// keys as problems of the layer so they are reported with the problems found once it has been merged
+func decodeProductFile(name string, contents []byte, vars map[string]string) (*productLayer, error) {
+	var (
+		document interface{}
+		err      error
+	)
+	switch path.Ext(name) {
+	case ".json":
+		err = json.Unmarshal(contents, &document)
+	case ".toml":
+		_, err = toml.Decode(string(contents), &document)
+	default:
+		err = yaml.Unmarshal(contents, &document)
+	}
+	if err != nil {
+		return nil, &ProductFileError{File: name, Problems: []Problem{{Message: err.Error()}}}
+	}
+
+	versionInfo := types.VersionInfo{}
+	switch path.Ext(name) {
+	case ".json":
+		err = json.Unmarshal(contents, &versionInfo)
+	case ".toml":
+		_, err = toml.Decode(string(contents), &versionInfo)
+	default:
+		err = yaml.Unmarshal(contents, &versionInfo)
+	}
+	if err != nil {
+		return nil, &ProductFileError{File: name, Problems: []Problem{{Message: err.Error()}}}
+	}
+
+	layer := &productLayer{name: name, versionInfo: &versionInfo, lines: newKeyLines(name, contents)}
+	problems := interpolate(&versionInfo, vars, layer.lines)
+	if len(problems) > 0 {
+		return nil, &ProductFileError{File: name, Problems: problems}
+	}
+	for _, field := range unknownFields(document, reflect.TypeOf(versionInfo), "") {
+		layer.problems = append(layer.problems, Problem{Line: layer.lines.of(field), Message: fmt.Sprintf("unknown field %s", field)})
+	}
+
+	return layer, nil
+}
+
+// validateDecoded - checks a product that was not read from its own file, such as inline products,
+// for unknown keys in document and semantic problems
+func validateDecoded(name string, document interface{}, versionInfo *types.VersionInfo) error {
+	var problems []Problem
+	for _, field := range unknownFields(document, reflect.TypeOf(*versionInfo), "") {
+		problems = append(problems, Problem{Message: fmt.Sprintf("unknown field %s", field)})
+	}
+	if len(versionInfo.Extends) > 0 {
+		problems = append(problems, Problem{Message: "extends is only supported in product files"})
+	}
+	for _, problem := range validateVersionInfo(versionInfo) {
+		problems = append(problems, problem.Problem)
+	}
+	if len(problems) > 0 {
+		return &ProductFileError{File: name, Problems: problems}
+	}
+
+	return nil
+}
+
+// checksumPattern - matches the hex encoding of a digest
+var checksumPattern = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+
+type fieldProblem struct {
+	Problem
+	field string
+}
+
+// validateVersionInfo - returns the semantic problems with a decoded product
+func validateVersionInfo(versionInfo *types.VersionInfo) []fieldProblem {
+	var problems []fieldProblem
+	required := func(field, value string) {
+		if len(strings.TrimSpace(value)) == 0 {
+			problems = append(problems, fieldProblem{Problem{Message: fmt.Sprintf("%s is required", field)}, field})
+		}
+	}
+	checksums := func(prefix string, checksums types.Checksums) {
+		for _, digest := range []struct {
+			field  string
+			value  string
+			length int
+		}{{"sha256", checksums.SHA256, 64}, {"sha1", checksums.SHA1, 40}, {"md5", checksums.MD5, 32}} {
+			field := prefix + digest.field
+			if len(digest.value) > 0 && (len(digest.value) != digest.length || !checksumPattern.MatchString(digest.value)) {
+				problems = append(problems, fieldProblem{Problem{Message: fmt.Sprintf("%s %q must be %d hexadecimal characters", field, digest.value, digest.length)}, field})
+			}
+		}
+	}
+	// versionRange - checks that a version that looks like a range parses, and that no checksums are pinned
+	// for a range, as they could only match one of the versions it resolves to
+	versionRange := func(field, value, prefix string, pinned types.Checksums) {
+		if file.IsVersionRange(value) {
+			for _, digest := range []struct{ name, value string }{{"sha256", pinned.SHA256}, {"sha1", pinned.SHA1}, {"md5", pinned.MD5}} {
+				if len(digest.value) > 0 {
+					problems = append(problems, fieldProblem{Problem{Message: fmt.Sprintf("%s can't be pinned for %s %q, which is a version range", prefix+digest.name, field, value)}, prefix + digest.name})
+				}
+			}
+			return
+		}
+		if strings.ContainsAny(value, "~^*<>|") {
+			_, err := semver.NewConstraint(value)
+			problems = append(problems, fieldProblem{Problem{Message: fmt.Sprintf("%s %q is not a valid version range: %s", field, value, err)}, field})
+		}
+	}
+	fileProvider := func(field string, value types.FileProviderEnum) {
+		switch value {
+		case types.FileProviderUnspecified, types.FileProviderPivnet, types.FileProviderS3, types.FileProviderHTTP:
+		default:
+			problems = append(problems, fieldProblem{Problem{Message: fmt.Sprintf("%s %q must be one of %s, %s or %s", field, value, types.FileProviderPivnet, types.FileProviderS3, types.FileProviderHTTP)}, field})
+		}
+	}
+	pattern := func(field, value string) {
+		if _, err := filepath.Match(value, ""); err != nil {
+			problems = append(problems, fieldProblem{Problem{Message: fmt.Sprintf("%s %q is not a valid glob pattern: %s", field, value, err)}, field})
+		}
+	}
+
+	required("version", versionInfo.Version)
+	versionRange("version", versionInfo.Version, "", types.Checksums{SHA256: versionInfo.SHA256, SHA1: versionInfo.SHA1, MD5: versionInfo.MD5})
+	required("product", versionInfo.PivotalProduct)
+	if len(versionInfo.Files) == 0 {
+		required("file_pattern", versionInfo.FilePattern)
+	}
+	pattern("file_pattern", versionInfo.FilePattern)
+	checksums("", types.Checksums{SHA256: versionInfo.SHA256, SHA1: versionInfo.SHA1, MD5: versionInfo.MD5})
+	fileProvider("file_provider", versionInfo.FileProvider)
+	for i, fileSpec := range versionInfo.Files {
+		field := fmt.Sprintf("files.%d", i)
+		required(field+".file_pattern", fileSpec.FilePattern)
+		if len(fileSpec.Version) > 0 {
+			versionRange(field+".version", fileSpec.Version, field+".", fileSpec.Checksums())
+		} else {
+			versionRange("version", versionInfo.Version, field+".", fileSpec.Checksums())
+		}
+		pattern(field+".file_pattern", fileSpec.FilePattern)
+		checksums(field+".", fileSpec.Checksums())
+		fileProvider(field+".file_provider", fileSpec.FileProvider)
+		directory := filepath.ToSlash(filepath.Clean(fileSpec.Directory))
+		if filepath.IsAbs(fileSpec.Directory) || directory == ".." || strings.HasPrefix(directory, "../") {
+			problems = append(problems, fieldProblem{Problem{Message: fmt.Sprintf("%s.directory %q must be a relative path within the destination", field, fileSpec.Directory)}, field + ".directory"})
+		}
+	}
+	versionRange("stemcell_version", versionInfo.StemcellVersion, "stemcell_", types.Checksums{SHA256: versionInfo.StemcellSHA256, SHA1: versionInfo.StemcellSHA1, MD5: versionInfo.StemcellMD5})
+	if len(versionInfo.StemcellVersion) > 0 {
+		required("stemcell_file_pattern", versionInfo.StemcellFilePattern)
+	}
+	pattern("stemcell_file_pattern", versionInfo.StemcellFilePattern)
+	checksums("stemcell_", types.Checksums{SHA256: versionInfo.StemcellSHA256, SHA1: versionInfo.StemcellSHA1, MD5: versionInfo.StemcellMD5})
+	for i, stemcell := range versionInfo.Stemcells {
+		field := fmt.Sprintf("stemcells.%d", i)
+		required(field+".version", stemcell.Version)
+		versionRange(field+".version", stemcell.Version, field+".", stemcell.Checksums())
+		required(field+".file_pattern", stemcell.FilePattern)
+		pattern(field+".file_pattern", stemcell.FilePattern)
+		checksums(field+".", stemcell.Checksums())
+		fileProvider(field+".file_provider", stemcell.FileProvider)
+	}
+
+	return problems
+}
+
+// unknownFields - returns the dotted names of keys in document that typ has no field for, in sorted order
+func unknownFields(document interface{}, typ reflect.Type, prefix string) []string {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	var unknown []string
+	switch typ.Kind() {
+	case reflect.Struct:
+		known := map[string]reflect.Type{}
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if len(name) > 0 && name != "-" {
+				known[name] = field.Type
+			}
+		}
+		for key, value := range documentMap(document) {
+			fieldType, ok := known[key]
+			if !ok {
+				unknown = append(unknown, prefix+key)
+				continue
+			}
+			unknown = append(unknown, unknownFields(value, fieldType, prefix+key+".")...)
+		}
+	case reflect.Slice:
+		// toml decodes arrays of tables as []map[string]interface{} rather than []interface{}
+		if items := reflect.ValueOf(document); items.Kind() == reflect.Slice {
+			for i := 0; i < items.Len(); i++ {
+				unknown = append(unknown, unknownFields(items.Index(i).Interface(), typ.Elem(), fmt.Sprintf("%s%d.", prefix, i))...)
+			}
+		}
+	case reflect.Map:
+		for key, value := range documentMap(document) {
+			unknown = append(unknown, unknownFields(value, typ.Elem(), prefix+key+".")...)
+		}
+	}
+	sort.Strings(unknown)
+
+	return unknown
+}
+
+// documentMap - normalises the map types produced by the yaml, json and toml decoders
+func documentMap(document interface{}) map[string]interface{} {
+	switch document := document.(type) {
+	case map[string]interface{}:
+		return document
+	case map[interface{}]interface{}:
+		result := map[string]interface{}{}
+		for key, value := range document {
+			result[fmt.Sprint(key)] = value
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// keyLines - finds the line on which a key is first written in a product file
+type keyLines struct {
+	lines  [][]byte
+	format string
+}
+
+func newKeyLines(name string, contents []byte) *keyLines {
+	return &keyLines{lines: bytes.Split(contents, []byte("\n")), format: path.Ext(name)}
+}
+
+// of - returns the 1-based line of the last segment of the dotted field name, found by looking for each segment
+// after the line of the one before it, or 0 when not found
+func (k *keyLines) of(field string) int {
+	segments := strings.Split(field, ".")
+	start := 0
+	for i, segment := range segments {
+		if index, err := strconv.Atoi(segment); err == nil && i > 0 {
+			start = k.item(segments[i-1], index, start)
+			continue
+		}
+		line := k.find(k.keyPattern(segment), start)
+		if line < 0 {
+			return k.find(k.keyPattern(segments[len(segments)-1]), 0) + 1
+		}
+		start = line
+	}
+
+	return start + 1
+}
+
+// item - returns the index of the line on which item index of the list key starts, searching from start
+func (k *keyLines) item(key string, index, start int) int {
+	var pattern *regexp.Regexp
+	switch k.format {
+	case ".json":
+		return start
+	case ".toml":
+		pattern = regexp.MustCompile(`^\s*\[\[\s*` + regexp.QuoteMeta(key) + `\s*\]\]`)
+		start = 0
+	default:
+		pattern = regexp.MustCompile(`^\s*-`)
+	}
+
+	for line := start; line >= 0 && line < len(k.lines); line++ {
+		line = k.find(pattern, line)
+		if line < 0 {
+			break
+		}
+		if index == 0 {
+			return line
+		}
+		index--
+	}
+
+	return start
+}
+
+func (k *keyLines) keyPattern(segment string) *regexp.Regexp {
+	key := regexp.QuoteMeta(segment)
+	switch k.format {
+	case ".json":
+		return regexp.MustCompile(`"` + key + `"\s*:`)
+	case ".toml":
+		return regexp.MustCompile(`^\s*"?` + key + `"?\s*=`)
+	default:
+		return regexp.MustCompile(`^[\s-]*["']?` + key + `["']?\s*:`)
+	}
+}
+
+// find - returns the index of the first line from start that matches pattern, or -1
+func (k *keyLines) find(pattern *regexp.Regexp, start int) int {
+	for i := start; i < len(k.lines); i++ {
+		if pattern.Match(k.lines[i]) {
+			return i
+		}
+	}
+
+	return -1
+}
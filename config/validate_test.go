@@ -0,0 +1,273 @@
+package config_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/file-downloader-resource/config"
+	"github.com/pivotalservices/file-downloader-resource/types"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestValidateProductFile(t *testing.T) {
+	spec.Run(t, "ValidateProductFile", testValidateProductFile, spec.Report(report.Terminal{}))
+}
+
+func testValidateProductFile(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	when("the product file is valid", func() {
+		it("returns the version info", func() {
+			versionInfo, err := config.ValidateProductFile("pas.yml", []byte("version: 2.1.5\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\nstemcell_version: \"3586.36\"\nstemcell_file_pattern: \"*vsphere*\"\n"), nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.StemcellVersion).Should(Equal("3586.36"))
+		})
+	})
+
+	when("the product file lists files", func() {
+		it("returns the entries", func() {
+			versionInfo, err := config.ValidateProductFile("om.yml", []byte("version: 0.42.0\nproduct: om\nfiles:\n- file_pattern: om-linux-*\n  directory: cli\n- file_pattern: \"*.pdf\"\n  product: om-docs\n  version: \"1.0\"\n"), nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.Files).Should(Equal([]types.FileSpec{
+				{FilePattern: "om-linux-*", Directory: "cli"},
+				{FilePattern: "*.pdf", PivotalProduct: "om-docs", Version: "1.0"},
+			}))
+		})
+
+		it("decodes whether to unpack each entry", func() {
+			for name, contents := range map[string]string{
+				"pas.yml":  "version: 2.1.5\nproduct: elastic-runtime\nfiles:\n- file_pattern: \"*.pdf\"\n  unpack: false\n- file_pattern: \"*.tgz\"\n",
+				"pas.toml": "version = \"2.1.5\"\nproduct = \"elastic-runtime\"\n\n[[files]]\nfile_pattern = \"*.pdf\"\nunpack = false\n\n[[files]]\nfile_pattern = \"*.tgz\"\n",
+			} {
+				versionInfo, err := config.ValidateProductFile(name, []byte(contents), nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(versionInfo.Files[0].ShouldUnpack(true)).Should(BeFalse())
+				Expect(versionInfo.Files[1].ShouldUnpack(true)).Should(BeTrue())
+			}
+		})
+
+		it("reports problems with the entries", func() {
+			_, err := config.ValidateProductFile("om.yml", []byte("version: 0.42.0\nproduct: om\nfiles:\n- directory: ../cli\n  prodcut: om\n"), nil)
+			Expect(err).Should(HaveOccurred())
+			Expect(err.(*config.ProductFileError).Problems).Should(Equal([]config.Problem{
+				{Line: 5, Message: "unknown field files.0.prodcut"},
+				{Message: "files.0.file_pattern is required"},
+				{Line: 4, Message: `files.0.directory "../cli" must be a relative path within the destination`},
+			}))
+		})
+	})
+
+	when("the product file lists stemcells", func() {
+		it("returns the shorthand stemcell followed by the list", func() {
+			versionInfo, err := config.ValidateProductFile("isolation-segment.yml", []byte(`version: 2.1.5
+product: p-isolation-segment
+file_pattern: p-isolation-segment-*.pivotal
+stemcell_version: "97.28"
+stemcell_file_pattern: "*vsphere*"
+stemcells:
+- product: stemcells-ubuntu-jammy
+  version: "1.90"
+  file_pattern: "*aws*"
+  tags:
+    iaas: aws
+`), nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.AllStemcells()).Should(Equal([]types.Stemcell{
+				{PivotalProduct: "stemcells", Version: "97.28", FilePattern: "*vsphere*"},
+				{PivotalProduct: "stemcells-ubuntu-jammy", Version: "1.90", FilePattern: "*aws*", Tags: map[string]string{"iaas": "aws"}},
+			}))
+		})
+
+		it("requires a version and pattern for each stemcell", func() {
+			_, err := config.ValidateProductFile("isolation-segment.yml", []byte("version: 2.1.5\nproduct: p-isolation-segment\nfile_pattern: p-*.pivotal\nstemcells:\n- tags:\n    iaas: aws\n"), nil)
+			Expect(err).Should(MatchError(ContainSubstring("stemcells.0.version is required")))
+			Expect(err).Should(MatchError(ContainSubstring("stemcells.0.file_pattern is required")))
+		})
+	})
+
+	when("the product file pins checksums", func() {
+		it("passes them on with each download", func() {
+			versionInfo, err := config.ValidateProductFile("pas.yml", []byte(`version: 2.1.5
+product: elastic-runtime
+file_pattern: cf-*.pivotal
+sha256: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+stemcell_version: "3586.36"
+stemcell_file_pattern: "*vsphere*"
+stemcell_sha1: a94a8fe5ccb19ba61c4c0873d391e987982fbbd3
+`), nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.Downloads()[0].Checksums()).Should(Equal(types.Checksums{SHA256: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"}))
+			Expect(versionInfo.AllStemcells()[0].Checksums()).Should(Equal(types.Checksums{SHA1: "a94a8fe5ccb19ba61c4c0873d391e987982fbbd3"}))
+		})
+
+		it("rejects digests that are not hex of the right length", func() {
+			_, err := config.ValidateProductFile("pas.yml", []byte("version: 2.1.5\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\nmd5: not-a-digest\nfiles:\n- file_pattern: \"*.pdf\"\n  sha256: abc123\n"), nil)
+			Expect(err).Should(MatchError(ContainSubstring(`pas.yml:4: md5 "not-a-digest" must be 32 hexadecimal characters`)))
+			Expect(err).Should(MatchError(ContainSubstring(`pas.yml:7: files.0.sha256 "abc123" must be 64 hexadecimal characters`)))
+		})
+	})
+
+	when("the product file uses version ranges", func() {
+		it("accepts semver ranges", func() {
+			_, err := config.ValidateProductFile("pas.yml", []byte("version: \"~2.1\"\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\nstemcell_version: \"3541.*\"\nstemcell_file_pattern: \"*vsphere*\"\n"), nil)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		it("rejects ranges that do not parse", func() {
+			_, err := config.ValidateProductFile("pas.yml", []byte("version: \"~2.1\"\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\nstemcell_version: \">=3541 <<\"\nstemcell_file_pattern: \"*vsphere*\"\n"), nil)
+			Expect(err).Should(MatchError(ContainSubstring(`pas.yml:4: stemcell_version ">=3541 <<" is not a valid version range`)))
+		})
+
+		it("accepts wildcard and hyphen ranges", func() {
+			versionInfo, err := config.ValidateProductFile("pas.yml", []byte("version: 2.1.x\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\nstemcell_version: 3541 - 3586\nstemcell_file_pattern: \"*vsphere*\"\n"), nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.Version).Should(Equal("2.1.x"))
+		})
+
+		it("rejects checksums pinned for a range", func() {
+			_, err := config.ValidateProductFile("pas.yml", []byte(`version: 2.1.x
+product: elastic-runtime
+file_pattern: cf-*.pivotal
+sha256: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+files:
+- file_pattern: "*.pdf"
+  md5: 098f6bcd4621d373cade4e832627b4f6
+- file_pattern: "*.yml"
+  version: 1.0.0
+  md5: 098f6bcd4621d373cade4e832627b4f6
+stemcells:
+- version: "3541.*"
+  file_pattern: "*aws*"
+  sha1: a94a8fe5ccb19ba61c4c0873d391e987982fbbd3
+`), nil)
+			Expect(err).Should(HaveOccurred())
+			Expect(err.(*config.ProductFileError).Problems).Should(Equal([]config.Problem{
+				{Line: 4, Message: `sha256 can't be pinned for version "2.1.x", which is a version range`},
+				{Line: 7, Message: `files.0.md5 can't be pinned for version "2.1.x", which is a version range`},
+				{Line: 14, Message: `stemcells.0.sha1 can't be pinned for stemcells.0.version "3541.*", which is a version range`},
+			}))
+		})
+	})
+
+	when("the product file overrides the file provider", func() {
+		it("decodes the settings of the product, its files and its stemcells", func() {
+			versionInfo, err := config.ValidateProductFile("pas.yml", []byte(`version: 2.1.5
+product: elastic-runtime
+file_pattern: cf-*.pivotal
+file_provider: pivnet
+files:
+- file_pattern: "*.yml"
+  file_provider: http
+  base_http_uri: https://mirror.example.com
+stemcells:
+- version: "3586.36"
+  product: stemcells
+  file_pattern: "*vsphere*"
+  file_provider: s3
+  bucket: stemcell-mirror
+  region_name: us-east-1
+  endpoint: https://s3.example.com
+`), nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.FileSettings()).Should(Equal(types.FileSettings{FileProvider: types.FileProviderPivnet}))
+			Expect(versionInfo.Files[0].FileSettings()).Should(Equal(types.FileSettings{FileProvider: types.FileProviderHTTP, BaseHTTPURI: "https://mirror.example.com"}))
+			Expect(versionInfo.Stemcells[0].FileSettings()).Should(Equal(types.FileSettings{FileProvider: types.FileProviderS3, Bucket: "stemcell-mirror", RegionName: "us-east-1", Endpoint: "https://s3.example.com"}))
+		})
+
+		it("rejects unknown file providers", func() {
+			_, err := config.ValidateProductFile("stemcells.yml", []byte("version: \"3586.36\"\nproduct: stemcells\nfile_pattern: \"*vsphere*\"\nfile_provider: ftp\n"), nil)
+			Expect(err).Should(MatchError(ContainSubstring(`stemcells.yml:4: file_provider "ftp" must be one of pivnet, s3 or http`)))
+		})
+
+		it("rejects unknown file providers for files and stemcells", func() {
+			_, err := config.ValidateProductFile("pas.yml", []byte("version: 2.1.5\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\nfiles:\n- file_pattern: \"*.yml\"\n  file_provider: ftp\nstemcells:\n- version: \"3586.36\"\n  file_pattern: \"*vsphere*\"\n  file_provider: scp\n"), nil)
+			Expect(err).Should(MatchError(ContainSubstring(`pas.yml:6: files.0.file_provider "ftp" must be one of pivnet, s3 or http`)))
+			Expect(err).Should(MatchError(ContainSubstring(`pas.yml:10: stemcells.0.file_provider "scp" must be one of pivnet, s3 or http`)))
+		})
+	})
+
+	when("the product file references variables", func() {
+		it("interpolates them before decoding", func() {
+			versionInfo, err := config.ValidateProductFile("pas.yml", []byte("version: 2.1.5\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\nstemcell_version: \"3586.36\"\nstemcell_file_pattern: \"*(( iaas ))*\"\n"), map[string]string{"iaas": "vsphere"})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.StemcellFilePattern).Should(Equal("*vsphere*"))
+		})
+
+		it("substitutes values as they are, without changing the structure of the file", func() {
+			versionInfo, err := config.ValidateProductFile("pas.yml", []byte("version: ((version))\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\n"), map[string]string{"version": "2.1.5\nendpoint: https://attacker.example.com # "})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.Version).Should(Equal("2.1.5\nendpoint: https://attacker.example.com # "))
+			Expect(versionInfo.Endpoint).Should(BeEmpty())
+		})
+
+		it("substitutes into every string value", func() {
+			versionInfo, err := config.ValidateProductFile("pas.yml", []byte("version: 2.1.5\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\nfiles:\n- file_pattern: \"*((iaas))*.yml\"\nstemcells:\n- version: \"3586.36\"\n  file_pattern: \"*((iaas))*\"\n  tags:\n    iaas: ((iaas))\n"), map[string]string{"iaas": "vsphere"})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.Files[0].FilePattern).Should(Equal("*vsphere*.yml"))
+			Expect(versionInfo.Stemcells[0].FilePattern).Should(Equal("*vsphere*"))
+			Expect(versionInfo.Stemcells[0].Tags).Should(Equal(map[string]string{"iaas": "vsphere"}))
+		})
+
+		it("ignores variables in comments", func() {
+			versionInfo, err := config.ValidateProductFile("pas.yml", []byte("# set ((iaas)) in vars\nversion: 2.1.5 # or ((next_version))\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\n"), nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.Version).Should(Equal("2.1.5"))
+		})
+	})
+
+	when("the product file has problems", func() {
+		it("reports every problem with its line", func() {
+			_, err := config.ValidateProductFile("pas.yml", []byte("version: 2.1.5\nfile_pattern: cf-[.pivotal\nstemcel_version: \"3586.36\"\n"), nil)
+			Expect(err).Should(HaveOccurred())
+			Expect(err.(*config.ProductFileError).Problems).Should(Equal([]config.Problem{
+				{Line: 3, Message: "unknown field stemcel_version"},
+				{Message: "product is required"},
+				{Line: 2, Message: `file_pattern "cf-[.pivotal" is not a valid glob pattern: syntax error in pattern`},
+			}))
+			Expect(err).Should(MatchError(ContainSubstring("pas.yml:3: unknown field stemcel_version")))
+		})
+
+		it("requires a stemcell pattern when a stemcell version is set", func() {
+			_, err := config.ValidateProductFile("pas.yml", []byte("version: 2.1.5\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\nstemcell_version: \"3586.36\"\n"), nil)
+			Expect(err).Should(MatchError(ContainSubstring("pas.yml: stemcell_file_pattern is required")))
+		})
+
+		it("reports unknown fields in json files", func() {
+			_, err := config.ValidateProductFile("pas.json", []byte("{\n  \"version\": \"2.1.5\",\n  \"product\": \"elastic-runtime\",\n  \"file_pattern\": \"cf-*.pivotal\",\n  \"stemcel_version\": \"3586.36\"\n}\n"), nil)
+			Expect(err).Should(MatchError(ContainSubstring("pas.json:5: unknown field stemcel_version")))
+		})
+
+		it("reports unknown fields in toml files", func() {
+			_, err := config.ValidateProductFile("pas.toml", []byte("version = \"2.1.5\"\nproduct = \"elastic-runtime\"\nfile_pattern = \"cf-*.pivotal\"\nstemcel_version = \"3586.36\"\n"), nil)
+			Expect(err).Should(MatchError(ContainSubstring("pas.toml:4: unknown field stemcel_version")))
+		})
+
+		it("reports unknown fields in toml arrays of tables", func() {
+			_, err := config.ValidateProductFile("pas.toml", []byte("version = \"2.1.5\"\nproduct = \"elastic-runtime\"\nfile_pattern = \"cf-*.pivotal\"\n\n[[files]]\nfile_pattern = \"om-linux-*\"\ndirectry = \"cli\"\n\n[[stemcells]]\nversion = \"3586.36\"\nfile_pattern = \"*vsphere*\"\ntag = { iaas = \"vsphere\" }\n"), nil)
+			Expect(err).Should(MatchError(ContainSubstring("unknown field files.0.directry")))
+			Expect(err).Should(MatchError(ContainSubstring("unknown field stemcells.0.tag")))
+		})
+
+		it("reports unresolved variables", func() {
+			_, err := config.ValidateProductFile("pas.yml", []byte("version: 2.1.5\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\nstemcell_version: ((stemcell_line)).36\nstemcell_file_pattern: \"*((iaas))*\"\n"), map[string]string{"stemcell_line": "3586"})
+			Expect(err).Should(HaveOccurred())
+			Expect(err.(*config.ProductFileError).Problems).Should(Equal([]config.Problem{
+				{Line: 5, Message: "unresolved variable ((iaas))"},
+			}))
+			Expect(err.(*config.ProductFileError).OnlyUnresolvedVariables()).Should(BeTrue())
+		})
+
+		it("reports unresolved variables in nested values with the line of their key", func() {
+			_, err := config.ValidateProductFile("pas.yml", []byte("version: 2.1.5\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\nstemcells:\n- version: \"3586.36\"\n  file_pattern: \"*((iaas))*\"\n"), nil)
+			Expect(err).Should(MatchError(ContainSubstring("pas.yml:6: unresolved variable ((iaas))")))
+		})
+
+		it("reports syntax errors", func() {
+			_, err := config.ValidateProductFile("pas.yml", []byte("version: [2.1.5\n"), nil)
+			Expect(err).Should(BeAssignableToTypeOf(&config.ProductFileError{}))
+		})
+	})
+}
@@ -0,0 +1,58 @@
+package file
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pivotalservices/file-downloader-resource/types"
+)
+
+// verifyFile - checks the downloaded file against each digest pinned in checksums,
+// removing it when any of them differ so a bad file is never left in the destination
+func verifyFile(filepath string, checksums types.Checksums) error {
+	digests := []struct {
+		name     string
+		expected string
+		hash     func() hash.Hash
+	}{
+		{"sha256", checksums.SHA256, sha256.New},
+		{"sha1", checksums.SHA1, sha1.New},
+		{"md5", checksums.MD5, md5.New},
+	}
+	for _, digest := range digests {
+		if digest.expected == "" {
+			continue
+		}
+		actual, err := sumFile(filepath, digest.hash())
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(actual, digest.expected) {
+			os.Remove(filepath)
+			return fmt.Errorf("%s checksum of %s does not match, expected %s but got %s", digest.name, filepath, digest.expected, actual)
+		}
+	}
+
+	return nil
+}
+
+func sumFile(filepath string, hash hash.Hash) (string, error) {
+	fileToSum, err := os.Open(filepath)
+	if err != nil {
+		return "", err
+	}
+	defer fileToSum.Close()
+
+	_, err = io.Copy(hash, fileToSum)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
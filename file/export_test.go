@@ -0,0 +1,8 @@
+package file
+
+import "io"
+
+// NewPivnetProviderWithClient - creates a pivnet provider that makes its calls with client
+func NewPivnetProviderWithClient(client pivnetClient, progressWriter io.Writer) Provider {
+	return &PivnetProvider{client: client, progressWriter: progressWriter}
+}
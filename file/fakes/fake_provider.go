@@ -5,40 +5,65 @@ import (
 	"sync"
 
 	"github.com/pivotalservices/file-downloader-resource/file"
+	"github.com/pivotalservices/file-downloader-resource/types"
 )
 
 type FakeProvider struct {
-	DownloadFileStub        func(targetDirectory, productSlug, version, pattern string, unpack bool) error
+	DownloadFileStub        func(targetDirectory, productSlug, version, pattern string, checksums types.Checksums, unpack bool) ([]string, error)
 	downloadFileMutex       sync.RWMutex
 	downloadFileArgsForCall []struct {
 		targetDirectory string
 		productSlug     string
 		version         string
 		pattern         string
+		checksums       types.Checksums
 		unpack          bool
 	}
 	downloadFileReturns struct {
-		result1 error
+		result1 []string
+		result2 error
+	}
+	VersionsStub        func(productSlug, pattern string) ([]string, error)
+	versionsMutex       sync.RWMutex
+	versionsArgsForCall []struct {
+		productSlug string
+		pattern     string
+	}
+	versionsReturns struct {
+		result1 []string
+		result2 error
+	}
+	FindFilesStub        func(productSlug, version, pattern string) ([]string, error)
+	findFilesMutex       sync.RWMutex
+	findFilesArgsForCall []struct {
+		productSlug string
+		version     string
+		pattern     string
+	}
+	findFilesReturns struct {
+		result1 []string
+		result2 error
 	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeProvider) DownloadFile(targetDirectory string, productSlug string, version string, pattern string, unpack bool) error {
+func (fake *FakeProvider) DownloadFile(targetDirectory string, productSlug string, version string, pattern string, checksums types.Checksums, unpack bool) ([]string, error) {
 	fake.downloadFileMutex.Lock()
 	fake.downloadFileArgsForCall = append(fake.downloadFileArgsForCall, struct {
 		targetDirectory string
 		productSlug     string
 		version         string
 		pattern         string
+		checksums       types.Checksums
 		unpack          bool
-	}{targetDirectory, productSlug, version, pattern, unpack})
-	fake.recordInvocation("DownloadFile", []interface{}{targetDirectory, productSlug, version, pattern, unpack})
+	}{targetDirectory, productSlug, version, pattern, checksums, unpack})
+	fake.recordInvocation("DownloadFile", []interface{}{targetDirectory, productSlug, version, pattern, checksums, unpack})
 	fake.downloadFileMutex.Unlock()
 	if fake.DownloadFileStub != nil {
-		return fake.DownloadFileStub(targetDirectory, productSlug, version, pattern, unpack)
+		return fake.DownloadFileStub(targetDirectory, productSlug, version, pattern, checksums, unpack)
 	} else {
-		return fake.downloadFileReturns.result1
+		return fake.downloadFileReturns.result1, fake.downloadFileReturns.result2
 	}
 }
 
@@ -48,17 +73,89 @@ func (fake *FakeProvider) DownloadFileCallCount() int {
 	return len(fake.downloadFileArgsForCall)
 }
 
-func (fake *FakeProvider) DownloadFileArgsForCall(i int) (string, string, string, string, bool) {
+func (fake *FakeProvider) DownloadFileArgsForCall(i int) (string, string, string, string, types.Checksums, bool) {
 	fake.downloadFileMutex.RLock()
 	defer fake.downloadFileMutex.RUnlock()
-	return fake.downloadFileArgsForCall[i].targetDirectory, fake.downloadFileArgsForCall[i].productSlug, fake.downloadFileArgsForCall[i].version, fake.downloadFileArgsForCall[i].pattern, fake.downloadFileArgsForCall[i].unpack
+	return fake.downloadFileArgsForCall[i].targetDirectory, fake.downloadFileArgsForCall[i].productSlug, fake.downloadFileArgsForCall[i].version, fake.downloadFileArgsForCall[i].pattern, fake.downloadFileArgsForCall[i].checksums, fake.downloadFileArgsForCall[i].unpack
 }
 
-func (fake *FakeProvider) DownloadFileReturns(result1 error) {
+func (fake *FakeProvider) DownloadFileReturns(result1 []string, result2 error) {
 	fake.DownloadFileStub = nil
 	fake.downloadFileReturns = struct {
-		result1 error
-	}{result1}
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeProvider) Versions(productSlug string, pattern string) ([]string, error) {
+	fake.versionsMutex.Lock()
+	fake.versionsArgsForCall = append(fake.versionsArgsForCall, struct {
+		productSlug string
+		pattern     string
+	}{productSlug, pattern})
+	fake.recordInvocation("Versions", []interface{}{productSlug, pattern})
+	fake.versionsMutex.Unlock()
+	if fake.VersionsStub != nil {
+		return fake.VersionsStub(productSlug, pattern)
+	} else {
+		return fake.versionsReturns.result1, fake.versionsReturns.result2
+	}
+}
+
+func (fake *FakeProvider) VersionsCallCount() int {
+	fake.versionsMutex.RLock()
+	defer fake.versionsMutex.RUnlock()
+	return len(fake.versionsArgsForCall)
+}
+
+func (fake *FakeProvider) VersionsArgsForCall(i int) (string, string) {
+	fake.versionsMutex.RLock()
+	defer fake.versionsMutex.RUnlock()
+	return fake.versionsArgsForCall[i].productSlug, fake.versionsArgsForCall[i].pattern
+}
+
+func (fake *FakeProvider) VersionsReturns(result1 []string, result2 error) {
+	fake.VersionsStub = nil
+	fake.versionsReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeProvider) FindFiles(productSlug string, version string, pattern string) ([]string, error) {
+	fake.findFilesMutex.Lock()
+	fake.findFilesArgsForCall = append(fake.findFilesArgsForCall, struct {
+		productSlug string
+		version     string
+		pattern     string
+	}{productSlug, version, pattern})
+	fake.recordInvocation("FindFiles", []interface{}{productSlug, version, pattern})
+	fake.findFilesMutex.Unlock()
+	if fake.FindFilesStub != nil {
+		return fake.FindFilesStub(productSlug, version, pattern)
+	} else {
+		return fake.findFilesReturns.result1, fake.findFilesReturns.result2
+	}
+}
+
+func (fake *FakeProvider) FindFilesCallCount() int {
+	fake.findFilesMutex.RLock()
+	defer fake.findFilesMutex.RUnlock()
+	return len(fake.findFilesArgsForCall)
+}
+
+func (fake *FakeProvider) FindFilesArgsForCall(i int) (string, string, string) {
+	fake.findFilesMutex.RLock()
+	defer fake.findFilesMutex.RUnlock()
+	return fake.findFilesArgsForCall[i].productSlug, fake.findFilesArgsForCall[i].version, fake.findFilesArgsForCall[i].pattern
+}
+
+func (fake *FakeProvider) FindFilesReturns(result1 []string, result2 error) {
+	fake.FindFilesStub = nil
+	fake.findFilesReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
 }
 
 func (fake *FakeProvider) Invocations() map[string][][]interface{} {
@@ -66,6 +163,10 @@ func (fake *FakeProvider) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.downloadFileMutex.RLock()
 	defer fake.downloadFileMutex.RUnlock()
+	fake.versionsMutex.RLock()
+	defer fake.versionsMutex.RUnlock()
+	fake.findFilesMutex.RLock()
+	defer fake.findFilesMutex.RUnlock()
 	return fake.invocations
 }
 
@@ -4,17 +4,20 @@ import (
 	"crypto/tls"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
 	"os"
 	"path"
+	"regexp"
 	"strings"
 	"syscall"
 
 	"github.com/pivotal-cf/go-pivnet/download"
 	"github.com/pivotal-cf/go-pivnet/logger"
 	"github.com/pivotal-cf/go-pivnet/logshim"
+	"github.com/pivotalservices/file-downloader-resource/types"
 	"github.com/shirou/gopsutil/disk"
 )
 
@@ -59,15 +62,67 @@ func NewHTTPProvider(skipSSLValidation bool, baseURL string) (Provider, error) {
 
 }
 
-func (h *HTTPProvider) DownloadFile(targetDirectory, productSlug, version, pattern string, unpack bool) error {
+func (h *HTTPProvider) DownloadFile(targetDirectory, productSlug, version, pattern string, checksums types.Checksums, unpack bool) ([]string, error) {
 
 	if err := os.MkdirAll(targetDirectory, os.ModePerm); err != nil {
-		return err
+		return nil, err
 	}
 	fileName := h.FileName(version, pattern)
 	contentURL := h.ContentURL(productSlug, version, pattern)
 	targetFile := path.Join(targetDirectory, fileName)
-	return h.Download(targetFile, contentURL)
+	err := h.Download(targetFile, contentURL)
+	if err != nil {
+		return nil, err
+	}
+	err = verifyFile(targetFile, checksums)
+	if err != nil {
+		return nil, err
+	}
+	return []string{targetFile}, nil
+}
+
+// indexLink - finds the targets of the links in an index page
+var indexLink = regexp.MustCompile(`href="([^"?#]+)"`)
+
+// Versions - lists the entries of the index page at the folder of productSlug, such as 2.1.5/
+func (h *HTTPProvider) Versions(productSlug, pattern string) ([]string, error) {
+	indexURL := fmt.Sprintf("%s/%s/", h.BaseURL, productSlug)
+	resp, err := h.HTTPClient.Get(indexURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bad status for url %s: %d", indexURL, resp.StatusCode)
+	}
+	index, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %s", err)
+	}
+
+	var versions []string
+	for _, link := range indexLink.FindAllStringSubmatch(string(index), -1) {
+		versions = append(versions, path.Base(strings.TrimSuffix(link[1], "/")))
+	}
+	return versions, nil
+}
+
+// FindFiles - checks that the file for version exists, without downloading it
+func (h *HTTPProvider) FindFiles(productSlug, version, pattern string) ([]string, error) {
+	contentURL := h.ContentURL(productSlug, version, pattern)
+	resp, err := h.HTTPClient.Head(contentURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make HEAD request: %s", err)
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return []string{h.FileName(version, pattern)}, nil
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("bad status for url %s: %d", contentURL, resp.StatusCode)
+	}
 }
 
 func (h *HTTPProvider) FileName(version, pattern string) string {
@@ -2,7 +2,9 @@ package file_test
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
@@ -14,6 +16,7 @@ import (
 	"github.com/onsi/gomega/ghttp"
 	"github.com/pivotal-cf/go-pivnet/logshim"
 	"github.com/pivotalservices/file-downloader-resource/file"
+	"github.com/pivotalservices/file-downloader-resource/types"
 	"github.com/sclevine/spec"
 	"github.com/sclevine/spec/report"
 )
@@ -85,4 +88,96 @@ func testHTTPProvider(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("listing versions", func() {
+		it("returns the entries of the index page of the product", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/elastic-runtime/"),
+					ghttp.RespondWith(http.StatusOK, `<html><body>
+<a href="../">../</a>
+<a href="2.1.5/">2.1.5/</a>
+<a href="/elastic-runtime/2.1.6/">2.1.6/</a>
+</body></html>`),
+				),
+			)
+			versions, err := provider.Versions("elastic-runtime", "cf-*.pivotal")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versions).Should(ContainElement("2.1.5"))
+			Expect(versions).Should(ContainElement("2.1.6"))
+		})
+
+		it("returns an error when there is no index", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusNotFound, ""))
+			_, err := provider.Versions("elastic-runtime", "cf-*.pivotal")
+			Expect(err).Should(MatchError(ContainSubstring("bad status")))
+		})
+	})
+
+	when("finding files", func() {
+		it("returns the file when it exists", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("HEAD", "/elastic-runtime/2.3.0/cf-2.3.0.pivotal"),
+				ghttp.RespondWith(http.StatusOK, ""),
+			))
+			files, err := provider.FindFiles("elastic-runtime", "2.3.0", "cf-*.pivotal")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(files).Should(Equal([]string{"cf-2.3.0.pivotal"}))
+		})
+
+		it("returns no files when it doesn't exist", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusNotFound, ""))
+			files, err := provider.FindFiles("elastic-runtime", "2.3.0", "cf-*.pivotal")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(files).Should(BeEmpty())
+		})
+	})
+
+	when("DownloadFile with pinned checksums", func() {
+		var (
+			bytes           []byte
+			targetDirectory string
+		)
+		it.Before(func() {
+			bytes = make([]byte, 40000)
+			rand.Read(bytes)
+			length := strconv.Itoa(len(bytes))
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("HEAD", "/elastic-runtime/2.3.0/cf-2.3.0.pivotal"),
+					func(w http.ResponseWriter, r *http.Request) {
+						w.Header().Add("Content-Length", length)
+					},
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/elastic-runtime/2.3.0/cf-2.3.0.pivotal"),
+					func(w http.ResponseWriter, r *http.Request) {
+						w.Write(bytes)
+					},
+				),
+			)
+			var err error
+			targetDirectory, err = ioutil.TempDir("", "http-provider")
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+		it.After(func() {
+			os.RemoveAll(targetDirectory)
+		})
+
+		it("keeps a file that matches", func() {
+			checksums := types.Checksums{SHA256: fmt.Sprintf("%x", sha256.Sum256(bytes))}
+			files, err := provider.DownloadFile(targetDirectory, "elastic-runtime", "2.3.0", "cf-*.pivotal", checksums, false)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(files).Should(Equal([]string{filepath.Join(targetDirectory, "cf-2.3.0.pivotal")}))
+			Expect(files[0]).Should(BeARegularFile())
+		})
+
+		it("deletes a file that does not match", func() {
+			checksums := types.Checksums{SHA256: fmt.Sprintf("%064x", 0)}
+			_, err := provider.DownloadFile(targetDirectory, "elastic-runtime", "2.3.0", "cf-*.pivotal", checksums, false)
+			Expect(err).Should(MatchError(ContainSubstring("sha256 checksum of")))
+			Expect(err).Should(MatchError(ContainSubstring("does not match")))
+			Expect(filepath.Join(targetDirectory, "cf-2.3.0.pivotal")).ShouldNot(BeAnExistingFile())
+		})
+	})
+
 }
@@ -1,7 +1,6 @@
 package file
 
 import (
-	"crypto/sha256"
 	"fmt"
 	"io"
 	"path/filepath"
@@ -13,10 +12,11 @@ import (
 	"github.com/fatih/color"
 	pivnetapi "github.com/pivotal-cf/go-pivnet"
 	"github.com/pivotal-cf/go-pivnet/logshim"
+	"github.com/pivotalservices/file-downloader-resource/types"
 )
 
 type PivnetProvider struct {
-	client         pivnetapi.Client
+	client         pivnetClient
 	progressWriter io.Writer
 	logger         *logshim.LogShim
 }
@@ -33,35 +33,108 @@ func NewPivnetProvider(token string) (Provider, error) {
 	ls := logshim.NewLogShim(logger, logger, false)
 	client := pivnetapi.NewClient(config, ls)
 	return &PivnetProvider{
-		client:         client,
+		client:         pivnetAPIClient{client},
 		progressWriter: os.Stderr,
 		logger:         ls,
 	}, nil
 }
 
-//DownloadFile - Downloads file based on version info
-func (p *PivnetProvider) DownloadFile(targetDirectory, productSlug, version, pattern string, unpack bool) error {
+// pivnetClient - the calls the provider makes to the Pivotal Network
+type pivnetClient interface {
+	ListReleases(productSlug string) ([]pivnetapi.Release, error)
+	ListProductFiles(productSlug string, releaseID int) ([]pivnetapi.ProductFile, error)
+	AcceptEULA(productSlug string, releaseID int) error
+	DownloadProductFile(location *os.File, productSlug string, releaseID, productFileID int, progressWriter io.Writer) error
+}
+
+// pivnetAPIClient - makes the calls with go-pivnet
+type pivnetAPIClient struct {
+	client pivnetapi.Client
+}
+
+func (c pivnetAPIClient) ListReleases(productSlug string) ([]pivnetapi.Release, error) {
+	return c.client.Releases.List(productSlug)
+}
+
+func (c pivnetAPIClient) ListProductFiles(productSlug string, releaseID int) ([]pivnetapi.ProductFile, error) {
+	return c.client.ProductFiles.ListForRelease(productSlug, releaseID)
+}
+
+func (c pivnetAPIClient) AcceptEULA(productSlug string, releaseID int) error {
+	return c.client.EULA.Accept(productSlug, releaseID)
+}
+
+func (c pivnetAPIClient) DownloadProductFile(location *os.File, productSlug string, releaseID, productFileID int, progressWriter io.Writer) error {
+	return c.client.ProductFiles.DownloadForRelease(location, productSlug, releaseID, productFileID, progressWriter)
+}
+
+//DownloadFile - Downloads file based on version info, verifying it against checksums, returning the paths of the downloaded files
+func (p *PivnetProvider) DownloadFile(targetDirectory, productSlug, version, pattern string, checksums types.Checksums, unpack bool) ([]string, error) {
+
+	release, err := p.release(productSlug, version)
+	if err != nil {
+		return nil, err
+	}
+	productFiles, err := p.client.ListProductFiles(productSlug, release.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = p.client.AcceptEULA(productSlug, release.ID)
+	if err != nil {
+		return nil, err
+	}
+	return p.downloadFiles(targetDirectory, pattern, productFiles, productSlug, release.ID, checksums, unpack)
+}
+
+//FindFiles - lists the names of the files of the release that match pattern, without downloading them
+func (p *PivnetProvider) FindFiles(productSlug, version, pattern string) ([]string, error) {
+	release, err := p.release(productSlug, version)
+	if err != nil {
+		return nil, err
+	}
+	productFiles, err := p.client.ListProductFiles(productSlug, release.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered, err := productFilesMatching(productFiles, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, pf := range filtered {
+		names = append(names, productFileName(pf))
+	}
+	return names, nil
+}
 
-	releases, err := p.client.Releases.List(productSlug)
+func (p *PivnetProvider) release(productSlug, version string) (pivnetapi.Release, error) {
+	releases, err := p.client.ListReleases(productSlug)
 	if err != nil {
-		return err
+		return pivnetapi.Release{}, err
 	}
 
 	for _, release := range releases {
 		if release.Version == version {
-			productFiles, err := p.client.ProductFiles.ListForRelease(productSlug, release.ID)
-			if err != nil {
-				return err
-			}
-			err = p.client.EULA.Accept(productSlug, release.ID)
-			if err != nil {
-				return err
-			}
-			return p.downloadFiles(targetDirectory, pattern, productFiles, productSlug, release.ID, unpack)
-
+			return release, nil
 		}
 	}
-	return fmt.Errorf("Release Version %s of product %s not found", version, productSlug)
+	return pivnetapi.Release{}, fmt.Errorf("Release Version %s of product %s not found", version, productSlug)
+}
+
+//Versions - lists the versions of the releases of productSlug
+func (p *PivnetProvider) Versions(productSlug, pattern string) ([]string, error) {
+	releases, err := p.client.ListReleases(productSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, release := range releases {
+		versions = append(versions, release.Version)
+	}
+	return versions, nil
 }
 
 func (p *PivnetProvider) downloadFiles(
@@ -70,78 +143,86 @@ func (p *PivnetProvider) downloadFiles(
 	productFiles []pivnetapi.ProductFile,
 	productSlug string,
 	releaseID int,
+	checksums types.Checksums,
 	unpack bool,
-) error {
+) ([]string, error) {
 
-	filtered := productFiles
+	filtered, err := productFileKeysByGlobs(productFiles, pattern)
+	if err != nil {
+		return nil, err
+	}
 
-	// If globs were not provided, download everything without filtering.
-	if pattern != "" {
-		var err error
-		filtered, err = productFileKeysByGlobs(productFiles, pattern)
-		if err != nil {
-			return err
-		}
+	if checksums.Pinned() && len(filtered) != 1 {
+		return nil, fmt.Errorf("pattern '%s' matches %d files but checksums can only be pinned for one", pattern, len(filtered))
 	}
 
 	if err := os.MkdirAll(targetDirectory, os.ModePerm); err != nil {
-		return err
+		return nil, err
 	}
 
+	var downloaded []string
 	for _, pf := range filtered {
-		parts := strings.Split(pf.AWSObjectKey, "/")
-		fileName := parts[len(parts)-1]
-		targetFile := filepath.Join(targetDirectory, fileName)
+		targetFile := filepath.Join(targetDirectory, productFileName(pf))
 		file, err := os.Create(targetFile)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		err = p.client.ProductFiles.DownloadForRelease(file, productSlug, releaseID, pf.ID, p.progressWriter)
+		err = p.client.DownloadProductFile(file, productSlug, releaseID, pf.ID, p.progressWriter)
+		file.Close()
 		if err != nil {
-			return err
+			return nil, err
 		}
+		err = verifyFile(targetFile, checksums)
+		if err != nil {
+			return nil, err
+		}
+		downloaded = append(downloaded, targetFile)
 		if unpack {
 			mime := archiveMimetype(targetFile)
 			if mime == "" {
-				return fmt.Errorf("not an archive: %s", targetFile)
+				return nil, fmt.Errorf("not an archive: %s", targetFile)
 			}
 			err = extractArchive(mime, targetFile)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 	}
-	return nil
+	return downloaded, nil
 }
 
-func sumFile(filepath string) (string, error) {
-	fileToSum, err := os.Open(filepath)
+func productFileKeysByGlobs(
+	productFiles []pivnetapi.ProductFile,
+	pattern string,
+) ([]pivnetapi.ProductFile, error) {
+
+	filtered, err := productFilesMatching(productFiles, pattern)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer fileToSum.Close()
 
-	hash := sha256.New()
-	_, err = io.Copy(hash, fileToSum)
-	if err != nil {
-		return "", err
+	if len(filtered) == 0 && pattern != "" {
+		return nil, fmt.Errorf("no match for pattern: '%s'", pattern)
 	}
 
-	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+	return filtered, nil
 }
 
-func productFileKeysByGlobs(
+// productFilesMatching - the product files whose names match pattern, or every product file when pattern is empty
+func productFilesMatching(
 	productFiles []pivnetapi.ProductFile,
 	pattern string,
 ) ([]pivnetapi.ProductFile, error) {
 
+	// If globs were not provided, match everything without filtering.
+	if pattern == "" {
+		return productFiles, nil
+	}
+
 	filtered := []pivnetapi.ProductFile{}
 
 	for _, p := range productFiles {
-		parts := strings.Split(p.AWSObjectKey, "/")
-		fileName := parts[len(parts)-1]
-
-		matched, err := filepath.Match(pattern, fileName)
+		matched, err := filepath.Match(pattern, productFileName(p))
 		if err != nil {
 			return nil, err
 		}
@@ -151,9 +232,10 @@ func productFileKeysByGlobs(
 		}
 	}
 
-	if len(filtered) == 0 && pattern != "" {
-		return nil, fmt.Errorf("no match for pattern: '%s'", pattern)
-	}
-
 	return filtered, nil
 }
+
+func productFileName(productFile pivnetapi.ProductFile) string {
+	parts := strings.Split(productFile.AWSObjectKey, "/")
+	return parts[len(parts)-1]
+}
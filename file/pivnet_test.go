@@ -0,0 +1,129 @@
+package file_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	pivnetapi "github.com/pivotal-cf/go-pivnet"
+	"github.com/pivotalservices/file-downloader-resource/file"
+	"github.com/pivotalservices/file-downloader-resource/types"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+type fakePivnetClient struct {
+	releases     []pivnetapi.Release
+	productFiles []pivnetapi.ProductFile
+	contents     map[int]string
+	downloaded   []int
+}
+
+func (c *fakePivnetClient) ListReleases(productSlug string) ([]pivnetapi.Release, error) {
+	return c.releases, nil
+}
+
+func (c *fakePivnetClient) ListProductFiles(productSlug string, releaseID int) ([]pivnetapi.ProductFile, error) {
+	return c.productFiles, nil
+}
+
+func (c *fakePivnetClient) AcceptEULA(productSlug string, releaseID int) error {
+	return nil
+}
+
+func (c *fakePivnetClient) DownloadProductFile(location *os.File, productSlug string, releaseID, productFileID int, progressWriter io.Writer) error {
+	c.downloaded = append(c.downloaded, productFileID)
+	_, err := location.WriteString(c.contents[productFileID])
+	return err
+}
+
+func TestPivnetProvider(t *testing.T) {
+	spec.Run(t, "PivnetProvider", testPivnetProvider, spec.Report(report.Terminal{}))
+}
+
+func testPivnetProvider(t *testing.T, when spec.G, it spec.S) {
+	var (
+		client          *fakePivnetClient
+		provider        file.Provider
+		targetDirectory string
+	)
+	it.Before(func() {
+		RegisterTestingT(t)
+		client = &fakePivnetClient{
+			releases: []pivnetapi.Release{{ID: 1, Version: "2.1.5"}, {ID: 2, Version: "2.1.6"}},
+			productFiles: []pivnetapi.ProductFile{
+				{ID: 10, AWSObjectKey: "product-files/elastic-runtime/cf-2.1.6-build.3.pivotal"},
+				{ID: 11, AWSObjectKey: "product-files/elastic-runtime/srt-2.1.6-build.3.pivotal"},
+				{ID: 12, AWSObjectKey: "product-files/elastic-runtime/cf-2.1.6-build.3.pdf"},
+			},
+			contents: map[int]string{10: "cf", 11: "srt", 12: "docs"},
+		}
+		provider = file.NewPivnetProviderWithClient(client, ioutil.Discard)
+		var err error
+		targetDirectory, err = ioutil.TempDir("", "pivnet")
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+	it.After(func() {
+		os.RemoveAll(targetDirectory)
+	})
+
+	when("downloading files", func() {
+		it("returns the path of each file matching the pattern", func() {
+			files, err := provider.DownloadFile(targetDirectory, "elastic-runtime", "2.1.6", "*.pivotal", types.Checksums{}, false)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(files).Should(Equal([]string{
+				filepath.Join(targetDirectory, "cf-2.1.6-build.3.pivotal"),
+				filepath.Join(targetDirectory, "srt-2.1.6-build.3.pivotal"),
+			}))
+			Expect(client.downloaded).Should(Equal([]int{10, 11}))
+			Expect(ioutil.ReadFile(files[1])).Should(Equal([]byte("srt")))
+		})
+
+		it("refuses to download when checksums are pinned for a pattern matching several files", func() {
+			_, err := provider.DownloadFile(targetDirectory, "elastic-runtime", "2.1.6", "*.pivotal", types.Checksums{MD5: "60b725f10c9c85c70d97880dfe8191b3"}, false)
+			Expect(err).Should(MatchError("pattern '*.pivotal' matches 2 files but checksums can only be pinned for one"))
+			Expect(client.downloaded).Should(BeEmpty())
+		})
+
+		it("removes the file and returns an error when its checksum does not match", func() {
+			_, err := provider.DownloadFile(targetDirectory, "elastic-runtime", "2.1.6", "cf-*.pivotal", types.Checksums{MD5: "60b725f10c9c85c70d97880dfe8191b3"}, false)
+			Expect(err).Should(MatchError(ContainSubstring("md5 checksum of %s does not match", filepath.Join(targetDirectory, "cf-2.1.6-build.3.pivotal"))))
+			Expect(filepath.Join(targetDirectory, "cf-2.1.6-build.3.pivotal")).ShouldNot(BeAnExistingFile())
+		})
+
+		it("downloads the file when it matches the pinned checksums", func() {
+			files, err := provider.DownloadFile(targetDirectory, "elastic-runtime", "2.1.6", "cf-*.pivotal", types.Checksums{MD5: "4e29342d9904d64e9e25fd3b92558e2f"}, false)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(files).Should(Equal([]string{filepath.Join(targetDirectory, "cf-2.1.6-build.3.pivotal")}))
+		})
+
+		it("returns an error when the version has no release", func() {
+			_, err := provider.DownloadFile(targetDirectory, "elastic-runtime", "2.2.0", "*.pivotal", types.Checksums{}, false)
+			Expect(err).Should(MatchError("Release Version 2.2.0 of product elastic-runtime not found"))
+		})
+	})
+
+	when("finding files", func() {
+		it("returns the name of each file matching the pattern without downloading it", func() {
+			names, err := provider.FindFiles("elastic-runtime", "2.1.6", "*.pivotal")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(names).Should(Equal([]string{"cf-2.1.6-build.3.pivotal", "srt-2.1.6-build.3.pivotal"}))
+			Expect(client.downloaded).Should(BeEmpty())
+		})
+
+		it("returns every file for an empty pattern, as downloading does", func() {
+			names, err := provider.FindFiles("elastic-runtime", "2.1.6", "")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(names).Should(HaveLen(3))
+		})
+
+		it("returns no files when nothing matches", func() {
+			names, err := provider.FindFiles("elastic-runtime", "2.1.6", "*.tgz")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(names).Should(BeEmpty())
+		})
+	})
+}
@@ -10,7 +10,9 @@ import (
 
 // Provider - defines the interface for how to fetch configuration
 type Provider interface {
-	DownloadFile(targetDirectory, productSlug, version, pattern string, unpack bool) error
+	DownloadFile(targetDirectory, productSlug, version, pattern string, checksums types.Checksums, unpack bool) ([]string, error)
+	Versions(productSlug, pattern string) ([]string, error)
+	FindFiles(productSlug, version, pattern string) ([]string, error)
 }
 
 const maxRetries = 12
@@ -33,3 +35,34 @@ func FromSource(source types.Source) (Provider, error) {
 		return nil, fmt.Errorf("unknown provider: %s", source.FileProvider)
 	}
 }
+
+// Providers - creates the file provider for each of the file provider settings that the files of a product use, once
+type Providers struct {
+	source  types.Source
+	create  func(types.Source) (Provider, error)
+	created map[types.FileSettings]Provider
+}
+
+// NewProviders - creates providers for source, with create making each of them
+func NewProviders(source types.Source, create func(types.Source) (Provider, error)) *Providers {
+	return &Providers{source: source, create: create, created: map[types.FileSettings]Provider{}}
+}
+
+// For - returns the provider for files with settings, which override those of source. The credentials of source
+// are sent to an overridden endpoint, so endpoints can only be overridden when source allows it
+func (p *Providers) For(settings types.FileSettings) (Provider, error) {
+	if provider, ok := p.created[settings]; ok {
+		return provider, nil
+	}
+	if len(settings.Endpoint) > 0 && settings.Endpoint != p.source.Endpoint && !p.source.AllowEndpointOverride {
+		return nil, fmt.Errorf("endpoint %s can only override the endpoint of source when allow_endpoint_override is set, as the credentials of source are sent to it", settings.Endpoint)
+	}
+
+	provider, err := p.create(settings.Apply(p.source))
+	if err != nil {
+		return nil, err
+	}
+	p.created[settings] = provider
+
+	return provider, nil
+}
@@ -0,0 +1,84 @@
+package file_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/file-downloader-resource/file"
+	"github.com/pivotalservices/file-downloader-resource/file/fakes"
+	"github.com/pivotalservices/file-downloader-resource/types"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestProviders(t *testing.T) {
+	spec.Run(t, "Providers", testProviders, spec.Report(report.Terminal{}))
+}
+
+func testProviders(t *testing.T, when spec.G, it spec.S) {
+	var (
+		source  types.Source
+		sources []types.Source
+		create  func(types.Source) (file.Provider, error)
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		source = types.Source{FileProvider: types.FileProviderS3, Bucket: "tiles", AccessKeyID: "key", SecretAccessKey: "secret"}
+		sources = nil
+		create = func(source types.Source) (file.Provider, error) {
+			sources = append(sources, source)
+			return &fakes.FakeProvider{}, nil
+		}
+	})
+
+	it("creates a provider with the settings applied over source", func() {
+		_, err := file.NewProviders(source, create).For(types.FileSettings{Bucket: "stemcell-mirror"})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(sources).Should(Equal([]types.Source{
+			{FileProvider: types.FileProviderS3, Bucket: "stemcell-mirror", AccessKeyID: "key", SecretAccessKey: "secret"},
+		}))
+	})
+
+	it("creates one provider for each of the settings", func() {
+		providers := file.NewProviders(source, create)
+		first, err := providers.For(types.FileSettings{})
+		Expect(err).ShouldNot(HaveOccurred())
+		second, err := providers.For(types.FileSettings{})
+		Expect(err).ShouldNot(HaveOccurred())
+		_, err = providers.For(types.FileSettings{Bucket: "stemcell-mirror"})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		Expect(second).Should(BeIdenticalTo(first))
+		Expect(sources).Should(HaveLen(2))
+	})
+
+	it("returns the error creating the provider", func() {
+		_, err := file.NewProviders(source, func(types.Source) (file.Provider, error) {
+			return nil, errors.New("no credentials")
+		}).For(types.FileSettings{})
+		Expect(err).Should(MatchError("no credentials"))
+	})
+
+	when("the settings override the endpoint", func() {
+		it("refuses to send the credentials of source to it", func() {
+			_, err := file.NewProviders(source, create).For(types.FileSettings{Endpoint: "https://s3.example.com"})
+			Expect(err).Should(MatchError(ContainSubstring("endpoint https://s3.example.com can only override the endpoint of source when allow_endpoint_override is set")))
+			Expect(sources).Should(BeEmpty())
+		})
+
+		it("allows the endpoint of source itself", func() {
+			source.Endpoint = "https://s3.example.com"
+			_, err := file.NewProviders(source, create).For(types.FileSettings{Endpoint: "https://s3.example.com"})
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		it("allows it when source opts in", func() {
+			source.AllowEndpointOverride = true
+			_, err := file.NewProviders(source, create).For(types.FileSettings{Endpoint: "https://s3.example.com"})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(sources[0].Endpoint).Should(Equal("https://s3.example.com"))
+		})
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 
 	"crypto/tls"
@@ -16,6 +17,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/pivotalservices/file-downloader-resource/types"
 	pb "gopkg.in/cheggaaa/pb.v1"
 )
 
@@ -26,6 +28,15 @@ type S3Provider struct {
 }
 
 func NewS3Provider(accessKeyID, secretAccessKey, regionName, endpoint, bucketName string, skipSSLVerification, disableSSL, useV2Signing bool) (Provider, error) {
+	return &S3Provider{
+		Client:         NewS3Client(accessKeyID, secretAccessKey, regionName, endpoint, skipSSLVerification, disableSSL, useV2Signing),
+		BucketName:     bucketName,
+		ProgressOutput: os.Stderr,
+	}, nil
+}
+
+// NewS3Client - creates an s3 client from source configuration
+func NewS3Client(accessKeyID, secretAccessKey, regionName, endpoint string, skipSSLVerification, disableSSL, useV2Signing bool) s3iface.S3API {
 	var creds *credentials.Credentials
 
 	if accessKeyID == "" && secretAccessKey == "" {
@@ -66,15 +77,11 @@ func NewS3Provider(accessKeyID, secretAccessKey, regionName, endpoint, bucketNam
 		setv2Handlers(client)
 	}
 
-	return &S3Provider{
-		Client:         client,
-		BucketName:     bucketName,
-		ProgressOutput: os.Stderr,
-	}, nil
+	return client
 }
 
-//DownloadFile - Downloads file based on version info
-func (p *S3Provider) DownloadFile(targetDirectory, productSlug, version, pattern string, unpack bool) error {
+//DownloadFile - Downloads file based on version info, verifying it against checksums, returning the path of the downloaded file
+func (p *S3Provider) DownloadFile(targetDirectory, productSlug, version, pattern string, checksums types.Checksums, unpack bool) ([]string, error) {
 
 	var (
 		localPath     string
@@ -82,7 +89,7 @@ func (p *S3Provider) DownloadFile(targetDirectory, productSlug, version, pattern
 		contentLength int64
 	)
 	if err := os.MkdirAll(targetDirectory, os.ModePerm); err != nil {
-		return err
+		return nil, err
 	}
 
 	bucketFiles, err := p.Client.ListObjects(&s3.ListObjectsInput{
@@ -91,13 +98,13 @@ func (p *S3Provider) DownloadFile(targetDirectory, productSlug, version, pattern
 	})
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	for _, bucketFile := range bucketFiles.Contents {
 		matched, err := Matches(*bucketFile.Key, productSlug, pattern, version)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if matched {
 			fileName := strings.Replace(*bucketFile.Key, productSlug+"/", "", 1)
@@ -112,7 +119,7 @@ func (p *S3Provider) DownloadFile(targetDirectory, productSlug, version, pattern
 		downloader := s3manager.NewDownloaderWithClient(p.Client)
 		localFile, err := os.Create(localPath)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		defer localFile.Close()
 
@@ -126,24 +133,77 @@ func (p *S3Provider) DownloadFile(targetDirectory, productSlug, version, pattern
 
 		_, err = downloader.Download(progressWriterAt{localFile, progress}, getObject)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		err = verifyFile(localPath, checksums)
+		if err != nil {
+			return nil, err
 		}
 
 		if unpack {
 			mime := archiveMimetype(localPath)
 			if mime == "" {
-				return fmt.Errorf("not an archive: %s", localPath)
+				return nil, fmt.Errorf("not an archive: %s", localPath)
 			}
 			err = extractArchive(mime, localPath)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 	} else {
-		return fmt.Errorf("No files found in bucket %s, folder %s matching pattern %s", p.BucketName, productSlug, pattern)
+		return nil, fmt.Errorf("No files found in bucket %s, folder %s matching pattern %s", p.BucketName, productSlug, pattern)
 	}
 
-	return nil
+	return []string{localPath}, nil
+}
+
+//FindFiles - lists the names of the files in the folder of productSlug matching pattern and version, without downloading them
+func (p *S3Provider) FindFiles(productSlug, version, pattern string) ([]string, error) {
+	bucketFiles, err := p.Client.ListObjects(&s3.ListObjectsInput{
+		Bucket: aws.String(p.BucketName),
+		Prefix: aws.String(productSlug),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, bucketFile := range bucketFiles.Contents {
+		matched, err := Matches(*bucketFile.Key, productSlug, pattern, version)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			names = append(names, strings.Replace(*bucketFile.Key, productSlug+"/", "", 1))
+		}
+	}
+	return names, nil
+}
+
+//Versions - lists the version numbers in the names of the files in the folder of productSlug matching pattern
+func (p *S3Provider) Versions(productSlug, pattern string) ([]string, error) {
+	bucketFiles, err := p.Client.ListObjects(&s3.ListObjectsInput{
+		Bucket: aws.String(p.BucketName),
+		Prefix: aws.String(productSlug),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, bucketFile := range bucketFiles.Contents {
+		if pattern != "" {
+			matched, err := filepath.Match(path.Join(productSlug, pattern), *bucketFile.Key)
+			if err != nil {
+				return nil, err
+			}
+			if !matched {
+				continue
+			}
+		}
+		names = append(names, strings.TrimPrefix(*bucketFile.Key, productSlug+"/"))
+	}
+	return versionsIn(names), nil
 }
 
 type progressWriterAt struct {
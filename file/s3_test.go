@@ -0,0 +1,101 @@
+package file_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/file-downloader-resource/file"
+	"github.com/pivotalservices/file-downloader-resource/types"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+type fakeS3Client struct {
+	s3iface.S3API
+	objects map[string]string
+}
+
+func (c *fakeS3Client) ListObjects(input *s3.ListObjectsInput) (*s3.ListObjectsOutput, error) {
+	output := &s3.ListObjectsOutput{}
+	for key, contents := range c.objects {
+		if strings.HasPrefix(key, aws.StringValue(input.Prefix)) {
+			output.Contents = append(output.Contents, &s3.Object{Key: aws.String(key), Size: aws.Int64(int64(len(contents)))})
+		}
+	}
+	return output, nil
+}
+
+func (c *fakeS3Client) GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, options ...request.Option) (*s3.GetObjectOutput, error) {
+	contents := c.objects[aws.StringValue(input.Key)]
+	return &s3.GetObjectOutput{
+		Body:          ioutil.NopCloser(bytes.NewBufferString(contents)),
+		ContentLength: aws.Int64(int64(len(contents))),
+	}, nil
+}
+
+func TestS3Provider(t *testing.T) {
+	spec.Run(t, "S3Provider", testS3Provider, spec.Report(report.Terminal{}))
+}
+
+func testS3Provider(t *testing.T, when spec.G, it spec.S) {
+	var (
+		provider        *file.S3Provider
+		targetDirectory string
+	)
+	it.Before(func() {
+		RegisterTestingT(t)
+		provider = &file.S3Provider{
+			Client: &fakeS3Client{objects: map[string]string{
+				"elastic-runtime/cf-2.1.6-build.3.pivotal": "cf",
+				"elastic-runtime/cf-2.1.5-build.1.pivotal": "old cf",
+			}},
+			BucketName:     "bucket",
+			ProgressOutput: ioutil.Discard,
+		}
+		var err error
+		targetDirectory, err = ioutil.TempDir("", "s3")
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+	it.After(func() {
+		os.RemoveAll(targetDirectory)
+	})
+
+	when("downloading a file", func() {
+		it("returns the path of the downloaded file", func() {
+			files, err := provider.DownloadFile(targetDirectory, "elastic-runtime", "2.1.6", "cf-*.pivotal", types.Checksums{}, false)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(files).Should(Equal([]string{filepath.Join(targetDirectory, "cf-2.1.6-build.3.pivotal")}))
+			Expect(ioutil.ReadFile(files[0])).Should(Equal([]byte("cf")))
+		})
+
+		it("keeps the file when it matches the pinned checksums", func() {
+			files, err := provider.DownloadFile(targetDirectory, "elastic-runtime", "2.1.6", "cf-*.pivotal", types.Checksums{SHA256: fmt.Sprintf("%x", sha256.Sum256([]byte("cf")))}, false)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(files[0]).Should(BeAnExistingFile())
+		})
+
+		it("removes the file and returns an error when its checksum does not match", func() {
+			path := filepath.Join(targetDirectory, "cf-2.1.6-build.3.pivotal")
+			wrong := fmt.Sprintf("%x", sha256.Sum256([]byte("tampered")))
+			_, err := provider.DownloadFile(targetDirectory, "elastic-runtime", "2.1.6", "cf-*.pivotal", types.Checksums{SHA256: wrong}, false)
+			Expect(err).Should(MatchError(fmt.Sprintf("sha256 checksum of %s does not match, expected %s but got %x", path, wrong, sha256.Sum256([]byte("cf")))))
+			Expect(path).ShouldNot(BeAnExistingFile())
+		})
+
+		it("returns an error when no file matches", func() {
+			_, err := provider.DownloadFile(targetDirectory, "elastic-runtime", "2.2.0", "cf-*.pivotal", types.Checksums{}, false)
+			Expect(err).Should(MatchError("No files found in bucket bucket, folder elastic-runtime matching pattern cf-*.pivotal"))
+		})
+	})
+}
@@ -0,0 +1,170 @@
+package file
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+
+	"github.com/Masterminds/semver"
+	"github.com/pivotalservices/file-downloader-resource/types"
+)
+
+// versionPattern - finds dotted version numbers such as 2.1.5 or 3541.25 in file names
+var versionPattern = regexp.MustCompile(`\d+(\.\d+)+`)
+
+// IsVersionRange - reports whether version is a semver range such as ~2.1 or 3541.* rather than a concrete version
+func IsVersionRange(version string) bool {
+	if _, err := semver.NewVersion(version); err == nil {
+		return false
+	}
+	_, err := semver.NewConstraint(version)
+	return err == nil
+}
+
+// ResolveVersion - returns version unchanged when it is concrete, otherwise the newest version of productSlug
+// that provider has and that satisfies the range
+func ResolveVersion(provider Provider, productSlug, pattern, version string) (string, error) {
+	if !IsVersionRange(version) {
+		return version, nil
+	}
+	constraint, err := semver.NewConstraint(version)
+	if err != nil {
+		return "", err
+	}
+
+	available, err := provider.Versions(productSlug, pattern)
+	if err != nil {
+		return "", err
+	}
+
+	var candidates []*semver.Version
+	names := map[*semver.Version]string{}
+	for _, name := range available {
+		candidate, err := semver.NewVersion(name)
+		if err != nil {
+			continue
+		}
+		if constraint.Check(candidate) {
+			candidates = append(candidates, candidate)
+			names[candidate] = name
+		}
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no version of product %s satisfies %s", productSlug, version)
+	}
+	sort.Sort(semver.Collection(candidates))
+
+	return names[candidates[len(candidates)-1]], nil
+}
+
+// Resolver - resolves version ranges, using the version pinned for a range when there is one,
+// and records the version each range resolved to
+type Resolver struct {
+	pinned   url.Values
+	resolved url.Values
+}
+
+// NewResolver - creates a resolver that uses the versions pinned by a previous resolution, as returned by Resolved
+func NewResolver(pinned string) (*Resolver, error) {
+	values, err := url.ParseQuery(pinned)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resolved versions %q: %s", pinned, err)
+	}
+
+	return &Resolver{pinned: values, resolved: url.Values{}}, nil
+}
+
+// Resolve - returns version unchanged when it is concrete, otherwise the version pinned for the range,
+// or the newest version of productSlug that provider has and that satisfies the range
+func (r *Resolver) Resolve(provider Provider, productSlug, pattern, version string) (string, error) {
+	if !IsVersionRange(version) {
+		return version, nil
+	}
+
+	key := rangeKey(productSlug, pattern, version)
+	concrete := r.pinned.Get(key)
+	if len(concrete) == 0 {
+		var err error
+		concrete, err = ResolveVersion(provider, productSlug, pattern, version)
+		if err != nil {
+			return "", err
+		}
+	}
+	r.resolved.Set(key, concrete)
+
+	return concrete, nil
+}
+
+// ResolveProduct - sets every version of versionInfo that is a range, including those of its files and stemcells,
+// to the version it resolves to with the provider for its file provider settings
+func (r *Resolver) ResolveProduct(providers *Providers, versionInfo *types.VersionInfo) error {
+	var err error
+	resolve := func(productSlug, pattern string, settings types.FileSettings, version *string) {
+		if err != nil || !IsVersionRange(*version) {
+			return
+		}
+		var provider Provider
+		provider, err = providers.For(settings)
+		if err == nil {
+			*version, err = r.Resolve(provider, productSlug, pattern, *version)
+		}
+	}
+
+	resolve(versionInfo.PivotalProduct, versionInfo.FilePattern, versionInfo.FileSettings(), &versionInfo.Version)
+	for i, file := range versionInfo.Files {
+		if len(file.Version) > 0 {
+			productSlug := file.PivotalProduct
+			if len(productSlug) == 0 {
+				productSlug = versionInfo.PivotalProduct
+			}
+			resolve(productSlug, file.FilePattern, file.FileSettings().Or(versionInfo.FileSettings()), &versionInfo.Files[i].Version)
+		}
+	}
+	resolve(versionInfo.StemcellProductPath(), versionInfo.StemcellFilePattern, versionInfo.FileSettings(), &versionInfo.StemcellVersion)
+	for i, stemcell := range versionInfo.Stemcells {
+		productSlug := stemcell.PivotalProduct
+		if len(productSlug) == 0 {
+			productSlug = versionInfo.StemcellProductPath()
+		}
+		resolve(productSlug, stemcell.FilePattern, stemcell.FileSettings().Or(versionInfo.FileSettings()), &versionInfo.Stemcells[i].Version)
+	}
+
+	return err
+}
+
+// Resolved - returns the version each range resolved to, encoded so that NewResolver can pin them
+func (r *Resolver) Resolved() string {
+	return r.resolved.Encode()
+}
+
+// Entries - returns "<product> <pattern> <range> <version>" for each range that was resolved, in order
+func (r *Resolver) Entries() []string {
+	var entries []string
+	for key, versions := range r.resolved {
+		entries = append(entries, key+" "+versions[0])
+	}
+	sort.Strings(entries)
+
+	return entries
+}
+
+func rangeKey(productSlug, pattern, version string) string {
+	return productSlug + " " + pattern + " " + version
+}
+
+// versionsIn - returns the distinct version numbers in names, in the order they are first found
+func versionsIn(names []string) []string {
+	var versions []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		for _, version := range versionPattern.FindAllString(name, -1) {
+			if !seen[version] {
+				seen[version] = true
+				versions = append(versions, version)
+			}
+		}
+	}
+
+	return versions
+}
@@ -0,0 +1,139 @@
+package file_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/file-downloader-resource/file"
+	"github.com/pivotalservices/file-downloader-resource/file/fakes"
+	"github.com/pivotalservices/file-downloader-resource/types"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestResolveVersion(t *testing.T) {
+	spec.Run(t, "ResolveVersion", testResolveVersion, spec.Report(report.Terminal{}))
+}
+
+func testResolveVersion(t *testing.T, when spec.G, it spec.S) {
+	var provider *fakes.FakeProvider
+	it.Before(func() {
+		RegisterTestingT(t)
+		provider = &fakes.FakeProvider{}
+		provider.VersionsReturns([]string{"2.0.9", "2.1.5", "2.1.12", "2.2.0", "2.1.13-rc.1", "not-a-version"}, nil)
+	})
+
+	when("the version is concrete", func() {
+		it("returns it without listing versions", func() {
+			Expect(file.ResolveVersion(provider, "elastic-runtime", "cf-*.pivotal", "2.1.5")).Should(Equal("2.1.5"))
+			Expect(file.ResolveVersion(provider, "stemcells", "*vsphere*", "3541.25")).Should(Equal("3541.25"))
+			Expect(provider.VersionsCallCount()).Should(Equal(0))
+		})
+	})
+
+	when("the version is a range", func() {
+		it("returns the newest version in the range", func() {
+			Expect(file.ResolveVersion(provider, "elastic-runtime", "cf-*.pivotal", "~2.1")).Should(Equal("2.1.12"))
+			Expect(file.ResolveVersion(provider, "elastic-runtime", "cf-*.pivotal", "2.*")).Should(Equal("2.2.0"))
+
+			productSlug, pattern := provider.VersionsArgsForCall(0)
+			Expect(productSlug).Should(Equal("elastic-runtime"))
+			Expect(pattern).Should(Equal("cf-*.pivotal"))
+		})
+
+		it("keeps the version as the provider lists it", func() {
+			provider.VersionsReturns([]string{"3541.24", "3541.25", "3586.36"}, nil)
+			Expect(file.ResolveVersion(provider, "stemcells", "*vsphere*", "3541.*")).Should(Equal("3541.25"))
+		})
+
+		it("returns an error when no version is in the range", func() {
+			_, err := file.ResolveVersion(provider, "elastic-runtime", "cf-*.pivotal", "~2.3")
+			Expect(err).Should(MatchError("no version of product elastic-runtime satisfies ~2.3"))
+		})
+
+		it("returns an error when versions can't be listed", func() {
+			provider.VersionsReturns(nil, errors.New("unavailable"))
+			_, err := file.ResolveVersion(provider, "elastic-runtime", "cf-*.pivotal", "~2.1")
+			Expect(err).Should(MatchError("unavailable"))
+		})
+	})
+}
+
+func TestResolver(t *testing.T) {
+	spec.Run(t, "Resolver", testResolver, spec.Report(report.Terminal{}))
+}
+
+func testResolver(t *testing.T, when spec.G, it spec.S) {
+	var (
+		provider    *fakes.FakeProvider
+		providers   *file.Providers
+		versionInfo *types.VersionInfo
+	)
+	it.Before(func() {
+		RegisterTestingT(t)
+		provider = &fakes.FakeProvider{}
+		providers = file.NewProviders(types.Source{}, func(types.Source) (file.Provider, error) {
+			return provider, nil
+		})
+		provider.VersionsStub = func(productSlug, pattern string) ([]string, error) {
+			if productSlug == "stemcells" {
+				return []string{"3541.24", "3541.25"}, nil
+			}
+			return []string{"2.1.5", "2.1.12", "2.2.0"}, nil
+		}
+		versionInfo = &types.VersionInfo{
+			Version:             "~2.1",
+			PivotalProduct:      "elastic-runtime",
+			FilePattern:         "cf-*.pivotal",
+			Files:               []types.FileSpec{{FilePattern: "*.pdf"}, {FilePattern: "srt-*.pivotal", Version: "2.1.x"}},
+			StemcellVersion:     "3541.*",
+			StemcellFilePattern: "*vsphere*",
+			Stemcells:           []types.Stemcell{{Version: "3541.24", FilePattern: "*aws*"}},
+		}
+	})
+
+	when("resolving a product", func() {
+		it("resolves every range of the product, its files and its stemcells", func() {
+			resolver, err := file.NewResolver("")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resolver.ResolveProduct(providers, versionInfo)).Should(Succeed())
+
+			Expect(versionInfo.Version).Should(Equal("2.1.12"))
+			Expect(versionInfo.Downloads()[1].Version).Should(Equal("2.1.12"))
+			Expect(versionInfo.Files[1].Version).Should(Equal("2.1.12"))
+			Expect(versionInfo.StemcellVersion).Should(Equal("3541.25"))
+			Expect(versionInfo.Stemcells[0].Version).Should(Equal("3541.24"))
+			Expect(resolver.Entries()).Should(Equal([]string{
+				"elastic-runtime cf-*.pivotal ~2.1 2.1.12",
+				"elastic-runtime srt-*.pivotal 2.1.x 2.1.12",
+				"stemcells *vsphere* 3541.* 3541.25",
+			}))
+		})
+
+		it("uses the versions pinned by a previous resolution instead of the newest", func() {
+			resolver, err := file.NewResolver("")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resolver.ResolveProduct(providers, versionInfo)).Should(Succeed())
+			pinned := resolver.Resolved()
+
+			provider.VersionsReturns([]string{"2.1.13", "3541.26"}, nil)
+			versionInfo.Version = "~2.1"
+			versionInfo.Files[1].Version = "2.1.x"
+			versionInfo.StemcellVersion = "3541.*"
+			resolver, err = file.NewResolver(pinned)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resolver.ResolveProduct(providers, versionInfo)).Should(Succeed())
+			Expect(versionInfo.Version).Should(Equal("2.1.12"))
+			Expect(versionInfo.StemcellVersion).Should(Equal("3541.25"))
+			Expect(resolver.Resolved()).Should(Equal(pinned))
+		})
+
+		it("returns an error when a range can't be resolved", func() {
+			versionInfo.StemcellVersion = "3586.*"
+			resolver, err := file.NewResolver("")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resolver.ResolveProduct(providers, versionInfo)).Should(MatchError("no version of product stemcells satisfies 3586.*"))
+		})
+	})
+}
@@ -2,7 +2,11 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/pivotalservices/file-downloader-resource/config"
 	"github.com/pivotalservices/file-downloader-resource/file"
@@ -31,6 +35,20 @@ func main() {
 		fatal("reading request", err)
 	}
 
+	if len(request.Params.Product) == 0 {
+		request.Params.Product = request.Source.Product
+	}
+	if len(request.Params.Vars) > 0 {
+		vars := map[string]string{}
+		for name, value := range request.Source.Vars {
+			vars[name] = value
+		}
+		for name, value := range request.Params.Vars {
+			vars[name] = value
+		}
+		request.Source.Vars = vars
+	}
+
 	configProvider, err := config.FromSource(request.Source)
 	if err != nil {
 		fatal("constructing config provider", err)
@@ -40,45 +58,159 @@ func main() {
 		fatal("getting version info", err)
 	}
 
-	fileProvider, err := file.FromSource(request.Source)
+	fileProviders := file.NewProviders(request.Source, file.FromSource)
+	resolver, err := file.NewResolver(request.Version.Resolved)
 	if err != nil {
-		fatal("constructing file provider", err)
+		fatal("reading version", err)
 	}
-	if request.Params.Stemcell {
-		err = fileProvider.DownloadFile(destination, versionInfo.StemcellProductPath(), versionInfo.StemcellVersion, versionInfo.StemcellFilePattern, request.Params.Unpack)
-		if err != nil {
-			fatal("downloading stemcell file", err)
+	err = resolver.ResolveProduct(fileProviders, versionInfo)
+	if err != nil {
+		fatal("resolving versions of "+request.Params.Product, err)
+	}
+
+	var downloaded []string
+	var stemcells []types.Stemcell
+	if request.Params.Stemcell.Selected() {
+		stemcells = versionInfo.SelectStemcells(request.Params.Stemcell)
+		if len(stemcells) == 0 {
+			fatal("selecting stemcells", fmt.Errorf("no stemcells of product %s match %v", request.Params.Product, request.Params.Stemcell.Tags))
+		}
+
+		for _, stemcell := range stemcells {
+			fileProvider, err := fileProviders.For(stemcell.FileSettings())
+			if err != nil {
+				fatal("constructing file provider for stemcell file "+stemcell.FilePattern, err)
+			}
+			files, err := fileProvider.DownloadFile(destination, stemcell.PivotalProduct, stemcell.Version, stemcell.FilePattern, stemcell.Checksums(), request.Params.Unpack)
+			if err != nil {
+				fatal("downloading stemcell file "+stemcell.FilePattern, err)
+			}
+			downloaded = append(downloaded, files...)
 		}
 	} else {
-		err = fileProvider.DownloadFile(destination, versionInfo.PivotalProduct, versionInfo.Version, versionInfo.FilePattern, request.Params.Unpack)
+		downloaded, err = downloadFiles(fileProviders, destination, versionInfo.Downloads(), request.Params.Unpack)
 		if err != nil {
-			fatal("downloading file", err)
+			fatal("downloading files", err)
 		}
 	}
 
-	if request.Params.Stemcell {
-		json.NewEncoder(os.Stdout).Encode(types.InResponse{
-			Version: request.Version,
-			Metadata: types.Metadata{
-				{Name: "resource_version", Value: VERSION},
-				{Name: "ref", Value: request.Version.Ref},
-				{Name: "product", Value: "stemcells"},
-				{Name: "product_version", Value: versionInfo.StemcellVersion},
-				{Name: "file_pattern", Value: versionInfo.StemcellFilePattern},
-			},
-		})
+	var metadata types.Metadata
+	if len(stemcells) == 1 {
+		metadata = types.Metadata{
+			{Name: "resource_version", Value: VERSION},
+			{Name: "ref", Value: request.Version.Ref},
+			{Name: "product", Value: stemcells[0].PivotalProduct},
+			{Name: "product_version", Value: stemcells[0].Version},
+			{Name: "file_pattern", Value: stemcells[0].FilePattern},
+		}
+	} else if len(stemcells) > 1 {
+		metadata = types.Metadata{
+			{Name: "resource_version", Value: VERSION},
+			{Name: "ref", Value: request.Version.Ref},
+		}
+		for _, stemcell := range stemcells {
+			metadata = append(metadata, types.MetadataField{Name: "stemcell", Value: fmt.Sprintf("%s %s %s", stemcell.PivotalProduct, stemcell.Version, stemcell.FilePattern)})
+		}
 	} else {
-		json.NewEncoder(os.Stdout).Encode(types.InResponse{
-			Version: request.Version,
-			Metadata: types.Metadata{
-				{Name: "resource_version", Value: VERSION},
-				{Name: "ref", Value: request.Version.Ref},
-				{Name: "product", Value: versionInfo.PivotalProduct},
-				{Name: "product_version", Value: versionInfo.Version},
-				{Name: "file_pattern", Value: versionInfo.FilePattern},
-			},
-		})
+		metadata = types.Metadata{
+			{Name: "resource_version", Value: VERSION},
+			{Name: "ref", Value: request.Version.Ref},
+			{Name: "product", Value: versionInfo.PivotalProduct},
+			{Name: "product_version", Value: versionInfo.Version},
+			{Name: "file_pattern", Value: versionInfo.FilePattern},
+		}
+	}
+	if versionInfo.FileProvider != types.FileProviderUnspecified {
+		metadata = append(metadata, types.MetadataField{Name: "file_provider", Value: string(versionInfo.FileProvider)})
+	}
+	for _, entry := range resolver.Entries() {
+		metadata = append(metadata, types.MetadataField{Name: "resolved_version", Value: entry})
+	}
+	for _, name := range relativeTo(destination, downloaded) {
+		metadata = append(metadata, types.MetadataField{Name: "file", Value: name})
+	}
+	if versionInfo.Provenance != nil {
+		metadata = append(metadata, types.MetadataField{Name: "config_files", Value: strings.Join(versionInfo.Provenance.Files, ",")})
+		if len(versionInfo.Provenance.Files) > 1 {
+			var fields []string
+			for field, file := range versionInfo.Provenance.Fields {
+				fields = append(fields, field+"="+file)
+			}
+			sort.Strings(fields)
+			metadata = append(metadata, types.MetadataField{Name: "config_provenance", Value: strings.Join(fields, ",")})
+		}
+	}
+	if len(request.Source.TagFilter) > 0 {
+		metadata = append(metadata, types.MetadataField{Name: "tag", Value: request.Version.Ref})
+	}
+
+	if commitInfoProvider, ok := configProvider.(config.CommitInfoProvider); ok {
+		commitInfo, err := commitInfoProvider.GetCommitInfo(request.Version.Ref)
+		if err != nil {
+			fatal("getting commit info", err)
+		}
+		metadata = append(metadata,
+			types.MetadataField{Name: "commit", Value: commitInfo.Ref},
+			types.MetadataField{Name: "author", Value: commitInfo.Author},
+			types.MetadataField{Name: "author_date", Value: commitInfo.Date},
+			types.MetadataField{Name: "subject", Value: commitInfo.Subject},
+			types.MetadataField{Name: "changed_products", Value: strings.Join(commitInfo.ChangedProducts, ",")},
+		)
+
+		err = writeCommitInfo(filepath.Join(destination, "config-commit.json"), commitInfo)
+		if err != nil {
+			fatal("writing config-commit.json", err)
+		}
+	}
+
+	json.NewEncoder(os.Stdout).Encode(types.InResponse{
+		Version:  request.Version,
+		Metadata: metadata,
+	})
+}
+
+// downloadFiles - downloads each of downloads into its directory under destination, with the file provider
+// for its settings, unpacking it when unpack is set and it doesn't set unpack itself, and returns the paths
+// of the downloaded files
+func downloadFiles(fileProviders *file.Providers, destination string, downloads []types.FileSpec, unpack bool) ([]string, error) {
+	var downloaded []string
+	for _, download := range downloads {
+		fileProvider, err := fileProviders.For(download.FileSettings())
+		if err != nil {
+			return nil, fmt.Errorf("downloading file %s: %s", download.FilePattern, err)
+		}
+		files, err := fileProvider.DownloadFile(filepath.Join(destination, download.Directory), download.PivotalProduct, download.Version, download.FilePattern, download.Checksums(), download.ShouldUnpack(unpack))
+		if err != nil {
+			return nil, fmt.Errorf("downloading file %s: %s", download.FilePattern, err)
+		}
+		downloaded = append(downloaded, files...)
 	}
+
+	return downloaded, nil
+}
+
+// relativeTo - returns the paths of files relative to destination
+func relativeTo(destination string, files []string) []string {
+	var names []string
+	for _, file := range files {
+		name, err := filepath.Rel(destination, file)
+		if err != nil {
+			name = file
+		}
+		names = append(names, name)
+	}
+
+	return names
+}
+
+func writeCommitInfo(path string, commitInfo *types.CommitInfo) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return json.NewEncoder(file).Encode(commitInfo)
 }
 
 func fatal(doing string, err error) {
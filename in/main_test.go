@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/file-downloader-resource/file"
+	"github.com/pivotalservices/file-downloader-resource/file/fakes"
+	"github.com/pivotalservices/file-downloader-resource/types"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestIn(t *testing.T) {
+	spec.Run(t, "in", testIn, spec.Report(report.Terminal{}))
+}
+
+func testIn(t *testing.T, when spec.G, it spec.S) {
+	var (
+		fileProvider  *fakes.FakeProvider
+		fileProviders *file.Providers
+		sources       []types.Source
+		destination   string
+	)
+	it.Before(func() {
+		RegisterTestingT(t)
+		fileProvider = &fakes.FakeProvider{}
+		sources = nil
+		fileProviders = file.NewProviders(types.Source{PivnetToken: "token"}, func(source types.Source) (file.Provider, error) {
+			sources = append(sources, source)
+			return fileProvider, nil
+		})
+		destination = filepath.Join("tmp", "build", "get")
+	})
+
+	when("downloading the files of a product", func() {
+		it("downloads each file into its directory and returns every downloaded path", func() {
+			fileProvider.DownloadFileStub = func(targetDirectory, productSlug, version, pattern string, checksums types.Checksums, unpack bool) ([]string, error) {
+				return []string{filepath.Join(targetDirectory, productSlug+"-"+version)}, nil
+			}
+			downloads := []types.FileSpec{
+				{FilePattern: "om-linux-*", PivotalProduct: "om", Version: "0.42.0", SHA256: "abc"},
+				{FilePattern: "*.pdf", PivotalProduct: "om-docs", Version: "1.0", Directory: "docs"},
+			}
+
+			downloaded, err := downloadFiles(fileProviders, destination, downloads, true)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(downloaded).Should(Equal([]string{
+				filepath.Join(destination, "om-0.42.0"),
+				filepath.Join(destination, "docs", "om-docs-1.0"),
+			}))
+			Expect(relativeTo(destination, downloaded)).Should(Equal([]string{"om-0.42.0", filepath.Join("docs", "om-docs-1.0")}))
+
+			Expect(fileProvider.DownloadFileCallCount()).Should(Equal(2))
+			targetDirectory, productSlug, version, pattern, checksums, unpack := fileProvider.DownloadFileArgsForCall(0)
+			Expect([]string{targetDirectory, productSlug, version, pattern}).Should(Equal([]string{destination, "om", "0.42.0", "om-linux-*"}))
+			Expect(checksums).Should(Equal(types.Checksums{SHA256: "abc"}))
+			Expect(unpack).Should(BeTrue())
+			targetDirectory, _, _, _, _, _ = fileProvider.DownloadFileArgsForCall(1)
+			Expect(targetDirectory).Should(Equal(filepath.Join(destination, "docs")))
+		})
+
+		it("downloads each file with the file provider for its settings", func() {
+			fileProvider.DownloadFileReturns([]string{filepath.Join(destination, "file")}, nil)
+			downloads := []types.FileSpec{
+				{FilePattern: "cf-*.pivotal", PivotalProduct: "elastic-runtime", Version: "2.1.5"},
+				{FilePattern: "*vsphere*", PivotalProduct: "stemcells", Version: "97.28", FileProvider: types.FileProviderS3, Bucket: "stemcell-mirror"},
+				{FilePattern: "*.pdf", PivotalProduct: "elastic-runtime", Version: "2.1.5"},
+			}
+
+			_, err := downloadFiles(fileProviders, destination, downloads, false)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(sources).Should(Equal([]types.Source{
+				{PivnetToken: "token"},
+				{PivnetToken: "token", FileProvider: types.FileProviderS3, Bucket: "stemcell-mirror"},
+			}))
+		})
+
+		it("unpacks the files that don't set unpack themselves when unpack is set", func() {
+			unpack := false
+			downloads := []types.FileSpec{
+				{FilePattern: "om-linux-*.tar.gz", PivotalProduct: "om", Version: "0.42.0", Directory: "cli"},
+				{FilePattern: "*.pdf", PivotalProduct: "elastic-runtime", Version: "2.1.5", Directory: "docs", Unpack: &unpack},
+			}
+
+			_, err := downloadFiles(fileProviders, destination, downloads, true)
+			Expect(err).ShouldNot(HaveOccurred())
+			_, _, _, _, _, unpackCLI := fileProvider.DownloadFileArgsForCall(0)
+			Expect(unpackCLI).Should(BeTrue())
+			_, _, _, _, _, unpackDocs := fileProvider.DownloadFileArgsForCall(1)
+			Expect(unpackDocs).Should(BeFalse())
+		})
+
+		it("returns an error naming the file that failed", func() {
+			fileProvider.DownloadFileReturns(nil, errors.New("no match for pattern: '*.pdf'"))
+
+			_, err := downloadFiles(fileProviders, destination, []types.FileSpec{{FilePattern: "*.pdf", PivotalProduct: "om-docs", Version: "1.0"}}, false)
+			Expect(err).Should(MatchError("downloading file *.pdf: no match for pattern: '*.pdf'"))
+		})
+	})
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pivotalservices/file-downloader-resource/config"
+	"github.com/pivotalservices/file-downloader-resource/file"
+	"github.com/pivotalservices/file-downloader-resource/types"
+)
+
+var VERSION = "0.0.0-dev"
+
+// report - the outcome of linting every product at a revision
+type report struct {
+	Ref      string          `json:"ref"`
+	Valid    bool            `json:"valid"`
+	Products []productReport `json:"products"`
+}
+
+// productReport - the problems with a product file and the files it resolves to
+type productReport struct {
+	Product  string           `json:"product"`
+	Valid    bool             `json:"valid"`
+	File     string           `json:"file,omitempty"`
+	Problems []config.Problem `json:"problems,omitempty"`
+	Files    []fileReport     `json:"files,omitempty"`
+}
+
+// fileReport - the files a pattern and version resolve to, which must be exactly one
+type fileReport struct {
+	Product     string   `json:"product"`
+	Version     string   `json:"version"`
+	FilePattern string   `json:"file_pattern"`
+	Matches     []string `json:"matches"`
+	Problem     string   `json:"problem,omitempty"`
+
+	settings types.FileSettings
+}
+
+func main() {
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	if flag.NArg() > 0 {
+		println("version: " + VERSION)
+		println("usage: " + os.Args[0] + " [-json] < request.json")
+		os.Exit(1)
+	}
+
+	var request types.CheckRequest
+	err := json.NewDecoder(os.Stdin).Decode(&request)
+	if err != nil {
+		fatal("reading request", err)
+	}
+
+	configProvider, err := config.FromSource(request.Source)
+	if err != nil {
+		fatal("constructing config provider", err)
+	}
+	lister, ok := configProvider.(config.ProductLister)
+	if !ok {
+		fatal("listing products", fmt.Errorf("the %s config provider can't list products", request.Source.ConfigProvider))
+	}
+
+	ref := request.Version.Ref
+	if len(ref) == 0 {
+		version, err := configProvider.LatestVersion()
+		if err != nil {
+			fatal("getting latest version", err)
+		}
+		ref = version.Ref
+	}
+
+	products, err := lister.ListProducts(ref)
+	if err != nil {
+		fatal("listing products", err)
+	}
+
+	fileProviders := file.NewProviders(request.Source, file.FromSource)
+	result := report{Ref: ref, Valid: true, Products: []productReport{}}
+	for _, product := range products {
+		productResult := lintProduct(configProvider, fileProviders, ref, product)
+		result.Valid = result.Valid && productResult.Valid
+		result.Products = append(result.Products, productResult)
+	}
+
+	if *asJSON {
+		json.NewEncoder(os.Stdout).Encode(result)
+	} else {
+		printReport(result)
+	}
+
+	if !result.Valid {
+		os.Exit(1)
+	}
+}
+
+// lintProduct - validates product and checks that each of its files and stemcells resolves to exactly one file
+func lintProduct(configProvider config.Provider, fileProviders *file.Providers, ref, product string) productReport {
+	result := productReport{Product: product, Valid: true}
+	versionInfo, err := configProvider.GetVersionInfo(ref, product)
+	if err != nil {
+		result.Valid = false
+		if productFileError, ok := err.(*config.ProductFileError); ok {
+			result.File = productFileError.File
+			result.Problems = productFileError.Problems
+		} else {
+			result.Problems = []config.Problem{{Message: err.Error()}}
+		}
+		return result
+	}
+
+	var files []fileReport
+	for _, download := range versionInfo.Downloads() {
+		files = append(files, fileReport{Product: download.PivotalProduct, Version: download.Version, FilePattern: download.FilePattern, settings: download.FileSettings()})
+	}
+	for _, stemcell := range versionInfo.AllStemcells() {
+		files = append(files, fileReport{Product: stemcell.PivotalProduct, Version: stemcell.Version, FilePattern: stemcell.FilePattern, settings: stemcell.FileSettings()})
+	}
+
+	for _, fileResult := range files {
+		fileResult.Matches = []string{}
+		var version string
+		fileProvider, err := fileProviders.For(fileResult.settings)
+		if err == nil {
+			version, err = file.ResolveVersion(fileProvider, fileResult.Product, fileResult.FilePattern, fileResult.Version)
+		}
+		if err == nil {
+			fileResult.Version = version
+			var matches []string
+			matches, err = fileProvider.FindFiles(fileResult.Product, version, fileResult.FilePattern)
+			fileResult.Matches = append(fileResult.Matches, matches...)
+		}
+		switch {
+		case err != nil:
+			fileResult.Problem = err.Error()
+		case len(fileResult.Matches) == 0:
+			fileResult.Problem = "matches no files"
+		case len(fileResult.Matches) > 1:
+			fileResult.Problem = fmt.Sprintf("matches %d files", len(fileResult.Matches))
+		}
+		if len(fileResult.Problem) > 0 {
+			result.Valid = false
+		}
+		result.Files = append(result.Files, fileResult)
+	}
+
+	return result
+}
+
+func printReport(result report) {
+	for _, product := range result.Products {
+		status := "ok"
+		if !product.Valid {
+			status = "invalid"
+		}
+		fmt.Printf("%s: %s\n", product.Product, status)
+		if len(product.File) > 0 {
+			lines := strings.Split((&config.ProductFileError{File: product.File, Problems: product.Problems}).Error(), "\n")
+			fmt.Println(strings.Join(lines[1:], "\n"))
+		} else {
+			for _, problem := range product.Problems {
+				fmt.Printf("  %s\n", problem.Message)
+			}
+		}
+		for _, fileResult := range product.Files {
+			outcome := strings.Join(fileResult.Matches, ", ")
+			if len(fileResult.Problem) > 0 {
+				outcome = fileResult.Problem
+				if len(fileResult.Matches) > 0 {
+					outcome += ": " + strings.Join(fileResult.Matches, ", ")
+				}
+			}
+			fmt.Printf("  %s %s %s: %s\n", fileResult.Product, fileResult.Version, fileResult.FilePattern, outcome)
+		}
+	}
+	fmt.Printf("%d products at %s, ", len(result.Products), result.Ref)
+	if result.Valid {
+		fmt.Println("all ok")
+	} else {
+		fmt.Println("some invalid")
+	}
+}
+
+func fatal(doing string, err error) {
+	println("error " + doing + ": " + err.Error())
+	os.Exit(1)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/file-downloader-resource/config"
+	configfakes "github.com/pivotalservices/file-downloader-resource/config/fakes"
+	"github.com/pivotalservices/file-downloader-resource/file"
+	filefakes "github.com/pivotalservices/file-downloader-resource/file/fakes"
+	"github.com/pivotalservices/file-downloader-resource/types"
+	"github.com/sclevine/spec"
+	specreport "github.com/sclevine/spec/report"
+)
+
+func TestLint(t *testing.T) {
+	spec.Run(t, "lint", testLint, spec.Report(specreport.Terminal{}))
+}
+
+func testLint(t *testing.T, when spec.G, it spec.S) {
+	var (
+		configProvider *configfakes.FakeProvider
+		fileProvider   *filefakes.FakeProvider
+		fileProviders  *file.Providers
+	)
+	it.Before(func() {
+		RegisterTestingT(t)
+		configProvider = &configfakes.FakeProvider{}
+		configProvider.GetVersionInfoReturns(&types.VersionInfo{Version: "2.1.5", PivotalProduct: "elastic-runtime", FilePattern: "cf-*.pivotal"}, nil)
+		fileProvider = &filefakes.FakeProvider{}
+		fileProviders = file.NewProviders(types.Source{}, func(types.Source) (file.Provider, error) {
+			return fileProvider, nil
+		})
+	})
+
+	it("is valid when each file matches exactly one file", func() {
+		fileProvider.FindFilesReturns([]string{"cf-2.1.5-build.3.pivotal"}, nil)
+
+		result := lintProduct(configProvider, fileProviders, "abc123", "pas")
+		Expect(result).Should(Equal(productReport{
+			Product: "pas",
+			Valid:   true,
+			Files: []fileReport{
+				{Product: "elastic-runtime", Version: "2.1.5", FilePattern: "cf-*.pivotal", Matches: []string{"cf-2.1.5-build.3.pivotal"}},
+			},
+		}))
+
+		revision, productName := configProvider.GetVersionInfoArgsForCall(0)
+		Expect(revision).Should(Equal("abc123"))
+		Expect(productName).Should(Equal("pas"))
+		productSlug, version, pattern := fileProvider.FindFilesArgsForCall(0)
+		Expect([]string{productSlug, version, pattern}).Should(Equal([]string{"elastic-runtime", "2.1.5", "cf-*.pivotal"}))
+	})
+
+	it("reports a file that matches no files", func() {
+		fileProvider.FindFilesReturns(nil, nil)
+
+		result := lintProduct(configProvider, fileProviders, "abc123", "pas")
+		Expect(result.Valid).Should(BeFalse())
+		Expect(result.Files[0].Problem).Should(Equal("matches no files"))
+		Expect(result.Files[0].Matches).Should(BeEmpty())
+	})
+
+	it("reports a file that matches more than one file", func() {
+		fileProvider.FindFilesReturns([]string{"cf-2.1.5-build.3.pivotal", "srt-2.1.5-build.3.pivotal"}, nil)
+
+		result := lintProduct(configProvider, fileProviders, "abc123", "pas")
+		Expect(result.Valid).Should(BeFalse())
+		Expect(result.Files[0].Problem).Should(Equal("matches 2 files"))
+		Expect(result.Files[0].Matches).Should(Equal([]string{"cf-2.1.5-build.3.pivotal", "srt-2.1.5-build.3.pivotal"}))
+	})
+
+	it("reports the error finding the files", func() {
+		fileProvider.FindFilesReturns(nil, errors.New("Release Version 2.1.5 of product elastic-runtime not found"))
+
+		result := lintProduct(configProvider, fileProviders, "abc123", "pas")
+		Expect(result.Valid).Should(BeFalse())
+		Expect(result.Files[0].Problem).Should(Equal("Release Version 2.1.5 of product elastic-runtime not found"))
+	})
+
+	it("finds the files of the version a range resolves to", func() {
+		configProvider.GetVersionInfoReturns(&types.VersionInfo{Version: "~2.1", PivotalProduct: "elastic-runtime", FilePattern: "cf-*.pivotal"}, nil)
+		fileProvider.VersionsReturns([]string{"2.1.5", "2.1.6", "2.2.0"}, nil)
+		fileProvider.FindFilesReturns([]string{"cf-2.1.6-build.3.pivotal"}, nil)
+
+		result := lintProduct(configProvider, fileProviders, "abc123", "pas")
+		Expect(result.Valid).Should(BeTrue())
+		Expect(result.Files[0].Version).Should(Equal("2.1.6"))
+		_, version, _ := fileProvider.FindFilesArgsForCall(0)
+		Expect(version).Should(Equal("2.1.6"))
+	})
+
+	it("reports the problems of an invalid product file", func() {
+		problems := []config.Problem{{File: "pas.yml", Line: 2, Message: "product is required"}}
+		configProvider.GetVersionInfoReturns(nil, &config.ProductFileError{File: "pas.yml", Problems: problems})
+
+		result := lintProduct(configProvider, fileProviders, "abc123", "pas")
+		Expect(result).Should(Equal(productReport{Product: "pas", Valid: false, File: "pas.yml", Problems: problems}))
+		Expect(fileProvider.FindFilesCallCount()).Should(Equal(0))
+	})
+
+	it("reports other errors getting the product", func() {
+		configProvider.GetVersionInfoReturns(nil, errors.New("reference not found"))
+
+		result := lintProduct(configProvider, fileProviders, "abc123", "pas")
+		Expect(result.Valid).Should(BeFalse())
+		Expect(result.Problems).Should(Equal([]config.Problem{{Message: "reference not found"}}))
+	})
+}
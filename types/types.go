@@ -1,7 +1,15 @@
 package types
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Version - a revision of the configuration, with the versions that the version ranges of the product
+// resolved to when check found it, so that every get of the version downloads the same files
 type Version struct {
-	Ref string `json:"ref"`
+	Ref      string `json:"ref"`
+	Resolved string `json:"resolved,omitempty"`
 }
 type CheckRequest struct {
 	Source  Source  `json:"source"`
@@ -17,9 +25,10 @@ type InRequest struct {
 }
 
 type InParams struct {
-	Product  string `json:"product"`
-	Stemcell bool   `json:"stemcell"`
-	Unpack   bool   `json:"unpack"`
+	Product  string            `json:"product"`
+	Stemcell StemcellSelector  `json:"stemcell"`
+	Unpack   bool              `json:"unpack"`
+	Vars     map[string]string `json:"vars"`
 }
 
 type InResponse struct {
@@ -39,32 +48,78 @@ type OutResponse struct {
 
 type Metadata []MetadataField
 
+// CommitInfo - describes the configuration change behind a version
+type CommitInfo struct {
+	Ref             string   `json:"ref"`
+	Author          string   `json:"author"`
+	Date            string   `json:"date"`
+	Subject         string   `json:"subject"`
+	ChangedProducts []string `json:"changed_products"`
+}
+
 type MetadataField struct {
 	Name  string `json:"name"`
 	Value string `json:"value"`
 }
 
 type Source struct {
-	ConfigProvider       ConfigProviderEnum `json:"config_provider"`
-	FileProvider         FileProviderEnum   `json:"file_provider"`
-	VersionRoot          string             `json:"version_root"`
-	URI                  string             `json:"uri"`
-	Branch               string             `json:"branch"`
-	PrivateKey           string             `json:"private_key"`
-	Username             string             `json:"username"`
-	Password             string             `json:"password"`
-	Path                 string             `json:"path"`
-	PivnetToken          string             `json:"pivnet_token"`
-	Bucket               string             `json:"bucket"`
-	AccessKeyID          string             `json:"access_key_id"`
-	SecretAccessKey      string             `json:"secret_access_key"`
-	RegionName           string             `json:"region_name"`
-	Endpoint             string             `json:"endpoint"`
-	DisableSSL           bool               `json:"disable_ssl"`
-	SkipSSLVerification  bool               `json:"skip_ssl_verification"`
-	ServerSideEncryption string             `json:"server_side_encryption"`
-	UseV2Signing         bool               `json:"use_v2_signing"`
-	BaseHTTPURI          string             `json:"base_http_uri"`
+	ConfigProvider         ConfigProviderEnum     `json:"config_provider"`
+	FileProvider           FileProviderEnum       `json:"file_provider"`
+	VersionRoot            string                 `json:"version_root"`
+	OverlayRoots           []string               `json:"overlay_roots"`
+	Product                string                 `json:"product"`
+	URI                    string                 `json:"uri"`
+	VersionedURI           string                 `json:"versioned_uri"`
+	Branch                 string                 `json:"branch"`
+	PrivateKey             string                 `json:"private_key"`
+	PrivateKeyPassphrase   string                 `json:"private_key_passphrase"`
+	KnownHosts             string                 `json:"known_hosts"`
+	HostKeyFingerprint     string                 `json:"host_key_fingerprint"`
+	Username               string                 `json:"username"`
+	Password               string                 `json:"password"`
+	AccessToken            string                 `json:"access_token"`
+	Path                   string                 `json:"path"`
+	Paths                  []string               `json:"paths"`
+	IgnorePaths            []string               `json:"ignore_paths"`
+	TagFilter              string                 `json:"tag_filter"`
+	CommitVerificationKeys []string               `json:"commit_verification_keys"`
+	PivnetToken            string                 `json:"pivnet_token"`
+	Bucket                 string                 `json:"bucket"`
+	AccessKeyID            string                 `json:"access_key_id"`
+	SecretAccessKey        string                 `json:"secret_access_key"`
+	RegionName             string                 `json:"region_name"`
+	Endpoint               string                 `json:"endpoint"`
+	DisableSSL             bool                   `json:"disable_ssl"`
+	SkipSSLVerification    bool                   `json:"skip_ssl_verification"`
+	ServerSideEncryption   string                 `json:"server_side_encryption"`
+	UseV2Signing           bool                   `json:"use_v2_signing"`
+	BaseHTTPURI            string                 `json:"base_http_uri"`
+	AllowEndpointOverride  bool                   `json:"allow_endpoint_override"`
+	Products               map[string]VersionInfo `json:"products"`
+	Vars                   map[string]string      `json:"vars"`
+
+	// ProductDocuments - products as they were written in source, to check them for unknown keys
+	ProductDocuments map[string]interface{} `json:"-"`
+}
+
+// UnmarshalJSON - decodes source, keeping the undecoded products as well
+func (s *Source) UnmarshalJSON(data []byte) error {
+	type plainSource Source
+	err := json.Unmarshal(data, (*plainSource)(s))
+	if err != nil {
+		return err
+	}
+
+	var documents struct {
+		Products map[string]interface{} `json:"products"`
+	}
+	err = json.Unmarshal(data, &documents)
+	if err != nil {
+		return err
+	}
+	s.ProductDocuments = documents.Products
+
+	return nil
 }
 
 type ConfigProviderEnum string
@@ -72,6 +127,10 @@ type ConfigProviderEnum string
 const (
 	ConfigProviderUnspecified ConfigProviderEnum = ""
 	ConfigProviderGit         ConfigProviderEnum = "git"
+	ConfigProviderDir         ConfigProviderEnum = "dir"
+	ConfigProviderS3          ConfigProviderEnum = "s3"
+	ConfigProviderHTTP        ConfigProviderEnum = "http"
+	ConfigProviderInline      ConfigProviderEnum = "inline"
 )
 
 type FileProviderEnum string
@@ -84,12 +143,223 @@ const (
 )
 
 type VersionInfo struct {
-	Version             string `yaml:"version"`
-	PivotalProduct      string `yaml:"product"`
-	FilePattern         string `yaml:"file_pattern"`
-	StemcellVersion     string `yaml:"stemcell_version"`
-	StemcellFilePattern string `yaml:"stemcell_file_pattern"`
-	StemcellProduct     string `yaml:"stemcell_product"`
+	Extends             string     `yaml:"extends" toml:"extends" json:"extends,omitempty"`
+	Version             string     `yaml:"version" toml:"version" json:"version"`
+	PivotalProduct      string     `yaml:"product" toml:"product" json:"product"`
+	FilePattern         string     `yaml:"file_pattern" toml:"file_pattern" json:"file_pattern"`
+	StemcellVersion     string     `yaml:"stemcell_version" toml:"stemcell_version" json:"stemcell_version,omitempty"`
+	StemcellFilePattern string     `yaml:"stemcell_file_pattern" toml:"stemcell_file_pattern" json:"stemcell_file_pattern,omitempty"`
+	StemcellProduct     string     `yaml:"stemcell_product" toml:"stemcell_product" json:"stemcell_product,omitempty"`
+	SHA256              string     `yaml:"sha256" toml:"sha256" json:"sha256,omitempty"`
+	SHA1                string     `yaml:"sha1" toml:"sha1" json:"sha1,omitempty"`
+	MD5                 string     `yaml:"md5" toml:"md5" json:"md5,omitempty"`
+	StemcellSHA256      string     `yaml:"stemcell_sha256" toml:"stemcell_sha256" json:"stemcell_sha256,omitempty"`
+	StemcellSHA1        string     `yaml:"stemcell_sha1" toml:"stemcell_sha1" json:"stemcell_sha1,omitempty"`
+	StemcellMD5         string     `yaml:"stemcell_md5" toml:"stemcell_md5" json:"stemcell_md5,omitempty"`
+	Files               []FileSpec `yaml:"files" toml:"files" json:"files,omitempty"`
+	Stemcells           []Stemcell `yaml:"stemcells" toml:"stemcells" json:"stemcells,omitempty"`
+
+	// FileProvider, Bucket, RegionName, Endpoint and BaseHTTPURI - override the file provider settings of source
+	// for the files and stemcells of this product
+	FileProvider FileProviderEnum `yaml:"file_provider" toml:"file_provider" json:"file_provider,omitempty"`
+	Bucket       string           `yaml:"bucket" toml:"bucket" json:"bucket,omitempty"`
+	RegionName   string           `yaml:"region_name" toml:"region_name" json:"region_name,omitempty"`
+	Endpoint     string           `yaml:"endpoint" toml:"endpoint" json:"endpoint,omitempty"`
+	BaseHTTPURI  string           `yaml:"base_http_uri" toml:"base_http_uri" json:"base_http_uri,omitempty"`
+
+	// Provenance - set by the configuration provider when the product was merged from several files
+	Provenance *Provenance `yaml:"-" toml:"-" json:"-"`
+}
+
+// FileSource - returns source with the file provider settings set by the product in place of its own
+func (v *VersionInfo) FileSource(source Source) Source {
+	return v.FileSettings().Apply(source)
+}
+
+// FileSettings - returns the file provider settings set by the product
+func (v *VersionInfo) FileSettings() FileSettings {
+	return FileSettings{FileProvider: v.FileProvider, Bucket: v.Bucket, RegionName: v.RegionName, Endpoint: v.Endpoint, BaseHTTPURI: v.BaseHTTPURI}
+}
+
+// FileSettings - file provider settings that override those of source, where empty settings are not overridden
+type FileSettings struct {
+	FileProvider FileProviderEnum
+	Bucket       string
+	RegionName   string
+	Endpoint     string
+	BaseHTTPURI  string
+}
+
+// Or - returns the settings with each one that is not set taken from defaults
+func (s FileSettings) Or(defaults FileSettings) FileSettings {
+	if s.FileProvider == FileProviderUnspecified {
+		s.FileProvider = defaults.FileProvider
+	}
+	if s.Bucket == "" {
+		s.Bucket = defaults.Bucket
+	}
+	if s.RegionName == "" {
+		s.RegionName = defaults.RegionName
+	}
+	if s.Endpoint == "" {
+		s.Endpoint = defaults.Endpoint
+	}
+	if s.BaseHTTPURI == "" {
+		s.BaseHTTPURI = defaults.BaseHTTPURI
+	}
+	return s
+}
+
+// Apply - returns source with the settings that are set in place of its own
+func (s FileSettings) Apply(source Source) Source {
+	if s.FileProvider != FileProviderUnspecified {
+		source.FileProvider = s.FileProvider
+	}
+	if s.Bucket != "" {
+		source.Bucket = s.Bucket
+	}
+	if s.RegionName != "" {
+		source.RegionName = s.RegionName
+	}
+	if s.Endpoint != "" {
+		source.Endpoint = s.Endpoint
+	}
+	if s.BaseHTTPURI != "" {
+		source.BaseHTTPURI = s.BaseHTTPURI
+	}
+	return source
+}
+
+// Provenance - the files a product was merged from, in order, and the file that set each field
+type Provenance struct {
+	Files  []string          `json:"files"`
+	Fields map[string]string `json:"fields"`
+}
+
+// FileSpec - an additional file to download with a product, where product and version default to those of the product
+type FileSpec struct {
+	FilePattern    string `yaml:"file_pattern" toml:"file_pattern" json:"file_pattern"`
+	PivotalProduct string `yaml:"product" toml:"product" json:"product,omitempty"`
+	Version        string `yaml:"version" toml:"version" json:"version,omitempty"`
+	Directory      string `yaml:"directory" toml:"directory" json:"directory,omitempty"`
+	SHA256         string `yaml:"sha256" toml:"sha256" json:"sha256,omitempty"`
+	SHA1           string `yaml:"sha1" toml:"sha1" json:"sha1,omitempty"`
+	MD5            string `yaml:"md5" toml:"md5" json:"md5,omitempty"`
+	Unpack         *bool  `yaml:"unpack" toml:"unpack" json:"unpack,omitempty"`
+
+	// FileProvider, Bucket, RegionName, Endpoint and BaseHTTPURI - override the file provider settings of the product for this file
+	FileProvider FileProviderEnum `yaml:"file_provider" toml:"file_provider" json:"file_provider,omitempty"`
+	Bucket       string           `yaml:"bucket" toml:"bucket" json:"bucket,omitempty"`
+	RegionName   string           `yaml:"region_name" toml:"region_name" json:"region_name,omitempty"`
+	Endpoint     string           `yaml:"endpoint" toml:"endpoint" json:"endpoint,omitempty"`
+	BaseHTTPURI  string           `yaml:"base_http_uri" toml:"base_http_uri" json:"base_http_uri,omitempty"`
+}
+
+// Checksums - returns the digests pinned for the file
+func (f FileSpec) Checksums() Checksums {
+	return Checksums{SHA256: f.SHA256, SHA1: f.SHA1, MD5: f.MD5}
+}
+
+// ShouldUnpack - returns whether to unpack the file, which unpack decides unless the file sets it
+func (f FileSpec) ShouldUnpack(unpack bool) bool {
+	if f.Unpack != nil {
+		return *f.Unpack
+	}
+	return unpack
+}
+
+// FileSettings - returns the file provider settings set for the file
+func (f FileSpec) FileSettings() FileSettings {
+	return FileSettings{FileProvider: f.FileProvider, Bucket: f.Bucket, RegionName: f.RegionName, Endpoint: f.Endpoint, BaseHTTPURI: f.BaseHTTPURI}
+}
+
+func (f *FileSpec) setFileSettings(settings FileSettings) {
+	f.FileProvider, f.Bucket, f.RegionName, f.Endpoint, f.BaseHTTPURI = settings.FileProvider, settings.Bucket, settings.RegionName, settings.Endpoint, settings.BaseHTTPURI
+}
+
+// Checksums - digests a downloaded file must have, where empty digests are not checked
+type Checksums struct {
+	SHA256 string
+	SHA1   string
+	MD5    string
+}
+
+// Pinned - reports whether any digest is set
+func (c Checksums) Pinned() bool {
+	return c.SHA256 != "" || c.SHA1 != "" || c.MD5 != ""
+}
+
+// Downloads - returns the product file, when file_pattern is set, followed by the entries of files
+// with their product, version and file provider settings defaulted
+func (v *VersionInfo) Downloads() []FileSpec {
+	var downloads []FileSpec
+	if v.FilePattern != "" {
+		download := FileSpec{FilePattern: v.FilePattern, PivotalProduct: v.PivotalProduct, Version: v.Version, SHA256: v.SHA256, SHA1: v.SHA1, MD5: v.MD5}
+		download.setFileSettings(v.FileSettings())
+		downloads = append(downloads, download)
+	}
+	for _, file := range v.Files {
+		if file.PivotalProduct == "" {
+			file.PivotalProduct = v.PivotalProduct
+		}
+		if file.Version == "" {
+			file.Version = v.Version
+		}
+		file.setFileSettings(file.FileSettings().Or(v.FileSettings()))
+		downloads = append(downloads, file)
+	}
+	return downloads
+}
+
+// Stemcell - a stemcell used by a product, with tags such as iaas: vsphere to select it by
+type Stemcell struct {
+	PivotalProduct string            `yaml:"product" toml:"product" json:"product,omitempty"`
+	Version        string            `yaml:"version" toml:"version" json:"version"`
+	FilePattern    string            `yaml:"file_pattern" toml:"file_pattern" json:"file_pattern"`
+	Tags           map[string]string `yaml:"tags" toml:"tags" json:"tags,omitempty"`
+	SHA256         string            `yaml:"sha256" toml:"sha256" json:"sha256,omitempty"`
+	SHA1           string            `yaml:"sha1" toml:"sha1" json:"sha1,omitempty"`
+	MD5            string            `yaml:"md5" toml:"md5" json:"md5,omitempty"`
+
+	// FileProvider, Bucket, RegionName, Endpoint and BaseHTTPURI - override the file provider settings of the product for this stemcell
+	FileProvider FileProviderEnum `yaml:"file_provider" toml:"file_provider" json:"file_provider,omitempty"`
+	Bucket       string           `yaml:"bucket" toml:"bucket" json:"bucket,omitempty"`
+	RegionName   string           `yaml:"region_name" toml:"region_name" json:"region_name,omitempty"`
+	Endpoint     string           `yaml:"endpoint" toml:"endpoint" json:"endpoint,omitempty"`
+	BaseHTTPURI  string           `yaml:"base_http_uri" toml:"base_http_uri" json:"base_http_uri,omitempty"`
+}
+
+// Checksums - returns the digests pinned for the stemcell
+func (s Stemcell) Checksums() Checksums {
+	return Checksums{SHA256: s.SHA256, SHA1: s.SHA1, MD5: s.MD5}
+}
+
+// FileSettings - returns the file provider settings set for the stemcell
+func (s Stemcell) FileSettings() FileSettings {
+	return FileSettings{FileProvider: s.FileProvider, Bucket: s.Bucket, RegionName: s.RegionName, Endpoint: s.Endpoint, BaseHTTPURI: s.BaseHTTPURI}
+}
+
+func (s *Stemcell) setFileSettings(settings FileSettings) {
+	s.FileProvider, s.Bucket, s.RegionName, s.Endpoint, s.BaseHTTPURI = settings.FileProvider, settings.Bucket, settings.RegionName, settings.Endpoint, settings.BaseHTTPURI
+}
+
+// AllStemcells - returns the stemcell_version shorthand, when set, followed by the entries of stemcells
+// with their product and file provider settings defaulted
+func (v *VersionInfo) AllStemcells() []Stemcell {
+	var stemcells []Stemcell
+	if v.StemcellVersion != "" {
+		stemcell := Stemcell{PivotalProduct: v.StemcellProductPath(), Version: v.StemcellVersion, FilePattern: v.StemcellFilePattern, SHA256: v.StemcellSHA256, SHA1: v.StemcellSHA1, MD5: v.StemcellMD5}
+		stemcell.setFileSettings(v.FileSettings())
+		stemcells = append(stemcells, stemcell)
+	}
+	for _, stemcell := range v.Stemcells {
+		if stemcell.PivotalProduct == "" {
+			stemcell.PivotalProduct = v.StemcellProductPath()
+		}
+		stemcell.setFileSettings(stemcell.FileSettings().Or(v.FileSettings()))
+		stemcells = append(stemcells, stemcell)
+	}
+	return stemcells
 }
 
 func (v *VersionInfo) StemcellProductPath() string {
@@ -98,3 +368,56 @@ func (v *VersionInfo) StemcellProductPath() string {
 	}
 	return v.StemcellProduct
 }
+
+// SelectStemcells - returns the stemcells of the product that selector matches, in order
+func (v *VersionInfo) SelectStemcells(selector StemcellSelector) []Stemcell {
+	var selected []Stemcell
+	for _, stemcell := range v.AllStemcells() {
+		if selector.Matches(stemcell) {
+			selected = append(selected, stemcell)
+		}
+	}
+	return selected
+}
+
+// StemcellSelector - the stemcell param of in, either true to select every stemcell of the product
+// or a map of tags that the selected stemcells must all have
+type StemcellSelector struct {
+	All  bool
+	Tags map[string]string
+}
+
+func (s *StemcellSelector) UnmarshalJSON(data []byte) error {
+	err := json.Unmarshal(data, &s.All)
+	if err == nil {
+		return nil
+	}
+
+	s.All = false
+	err = json.Unmarshal(data, &s.Tags)
+	if err != nil {
+		return fmt.Errorf("stemcell must be true, false or a map of tags: %s", err)
+	}
+	if s.Tags != nil && len(s.Tags) == 0 {
+		return fmt.Errorf("stemcell must name at least one tag, or be true to select every stemcell")
+	}
+	return nil
+}
+
+// Selected - reports whether stemcells should be downloaded instead of the product
+func (s StemcellSelector) Selected() bool {
+	return s.All || len(s.Tags) > 0
+}
+
+// Matches - reports whether stemcell is selected
+func (s StemcellSelector) Matches(stemcell Stemcell) bool {
+	if s.All {
+		return true
+	}
+	for name, value := range s.Tags {
+		if stemcell.Tags[name] != value {
+			return false
+		}
+	}
+	return len(s.Tags) > 0
+}
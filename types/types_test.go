@@ -0,0 +1,186 @@
+package types_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pivotalservices/file-downloader-resource/types"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestVersionInfo(t *testing.T) {
+	spec.Run(t, "VersionInfo", testVersionInfo, spec.Report(report.Terminal{}))
+}
+
+func testVersionInfo(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	when("listing the files to download", func() {
+		it("returns the product file followed by the entries of files with product and version defaulted", func() {
+			versionInfo := types.VersionInfo{
+				Version:        "0.42.0",
+				PivotalProduct: "om",
+				FilePattern:    "om-darwin-*",
+				SHA256:         "abc",
+				Files: []types.FileSpec{
+					{FilePattern: "om-linux-*", Directory: "cli"},
+					{FilePattern: "*.pdf", PivotalProduct: "om-docs", Version: "1.0"},
+				},
+			}
+			Expect(versionInfo.Downloads()).Should(Equal([]types.FileSpec{
+				{FilePattern: "om-darwin-*", PivotalProduct: "om", Version: "0.42.0", SHA256: "abc"},
+				{FilePattern: "om-linux-*", PivotalProduct: "om", Version: "0.42.0", Directory: "cli"},
+				{FilePattern: "*.pdf", PivotalProduct: "om-docs", Version: "1.0"},
+			}))
+		})
+
+		it("leaves out the product file when it has no file_pattern", func() {
+			versionInfo := types.VersionInfo{Version: "0.42.0", PivotalProduct: "om", Files: []types.FileSpec{{FilePattern: "om-linux-*"}}}
+			Expect(versionInfo.Downloads()).Should(Equal([]types.FileSpec{{FilePattern: "om-linux-*", PivotalProduct: "om", Version: "0.42.0"}}))
+		})
+
+		it("defaults the file provider settings of each file to those of the product", func() {
+			versionInfo := types.VersionInfo{
+				Version:        "2.1.5",
+				PivotalProduct: "elastic-runtime",
+				FilePattern:    "cf-*.pivotal",
+				FileProvider:   types.FileProviderS3,
+				Bucket:         "tiles",
+				Files: []types.FileSpec{
+					{FilePattern: "*.yml"},
+					{FilePattern: "*.pdf", Bucket: "docs"},
+					{FilePattern: "*.txt", FileProvider: types.FileProviderHTTP, BaseHTTPURI: "https://mirror.example.com"},
+				},
+			}
+			var settings []types.FileSettings
+			for _, download := range versionInfo.Downloads() {
+				settings = append(settings, download.FileSettings())
+			}
+			Expect(settings).Should(Equal([]types.FileSettings{
+				{FileProvider: types.FileProviderS3, Bucket: "tiles"},
+				{FileProvider: types.FileProviderS3, Bucket: "tiles"},
+				{FileProvider: types.FileProviderS3, Bucket: "docs"},
+				{FileProvider: types.FileProviderHTTP, Bucket: "tiles", BaseHTTPURI: "https://mirror.example.com"},
+			}))
+		})
+	})
+
+	when("listing the stemcells", func() {
+		it("defaults the file provider settings of each stemcell to those of the product", func() {
+			versionInfo := types.VersionInfo{
+				Version:             "2.1.5",
+				PivotalProduct:      "elastic-runtime",
+				FileProvider:        types.FileProviderPivnet,
+				StemcellVersion:     "3586.36",
+				StemcellFilePattern: "*vsphere*",
+				Stemcells: []types.Stemcell{
+					{Version: "97.28", FilePattern: "*vsphere*", FileProvider: types.FileProviderS3, Bucket: "stemcell-mirror"},
+				},
+			}
+			var settings []types.FileSettings
+			for _, stemcell := range versionInfo.AllStemcells() {
+				settings = append(settings, stemcell.FileSettings())
+			}
+			Expect(settings).Should(Equal([]types.FileSettings{
+				{FileProvider: types.FileProviderPivnet},
+				{FileProvider: types.FileProviderS3, Bucket: "stemcell-mirror"},
+			}))
+		})
+	})
+
+	when("building the source of the file provider", func() {
+		it("applies the settings of the product over source", func() {
+			versionInfo := types.VersionInfo{Version: "3586.36", PivotalProduct: "stemcells", FilePattern: "*vsphere*", FileProvider: types.FileProviderS3, Bucket: "stemcell-mirror"}
+			source := versionInfo.FileSource(types.Source{FileProvider: types.FileProviderPivnet, PivnetToken: "token", Bucket: "tiles", RegionName: "us-east-1"})
+			Expect(source).Should(Equal(types.Source{FileProvider: types.FileProviderS3, PivnetToken: "token", Bucket: "stemcell-mirror", RegionName: "us-east-1"}))
+		})
+	})
+}
+
+func TestStemcellSelector(t *testing.T) {
+	spec.Run(t, "StemcellSelector", testStemcellSelector, spec.Report(report.Terminal{}))
+}
+
+func testStemcellSelector(t *testing.T, when spec.G, it spec.S) {
+	var (
+		params      types.InParams
+		vsphere     types.Stemcell
+		aws         types.Stemcell
+		windows     types.Stemcell
+		versionInfo types.VersionInfo
+	)
+	it.Before(func() {
+		RegisterTestingT(t)
+		params = types.InParams{}
+		vsphere = types.Stemcell{PivotalProduct: "stemcells-ubuntu-xenial", Version: "97.28", Tags: map[string]string{"iaas": "vsphere", "os": "xenial"}}
+		aws = types.Stemcell{PivotalProduct: "stemcells-ubuntu-xenial", Version: "97.28", Tags: map[string]string{"iaas": "aws", "os": "xenial"}}
+		windows = types.Stemcell{PivotalProduct: "stemcells-windows-server", Version: "1803.5", Tags: map[string]string{"iaas": "vsphere", "os": "windows"}}
+		versionInfo = types.VersionInfo{Stemcells: []types.Stemcell{vsphere, aws, windows}}
+	})
+
+	when("stemcell is true", func() {
+		it("selects every stemcell", func() {
+			err := json.Unmarshal([]byte(`{"stemcell": true}`), &params)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(params.Stemcell.Selected()).Should(BeTrue())
+			Expect(versionInfo.SelectStemcells(params.Stemcell)).Should(Equal([]types.Stemcell{vsphere, aws, windows}))
+		})
+	})
+
+	when("stemcell is false or missing", func() {
+		it("selects no stemcells", func() {
+			err := json.Unmarshal([]byte(`{"stemcell": false}`), &params)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(params.Stemcell.Selected()).Should(BeFalse())
+
+			err = json.Unmarshal([]byte(`{}`), &params)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(params.Stemcell.Selected()).Should(BeFalse())
+			Expect(versionInfo.SelectStemcells(params.Stemcell)).Should(BeEmpty())
+		})
+	})
+
+	when("stemcell is a map of tags", func() {
+		it("selects the stemcells with all of the tags", func() {
+			err := json.Unmarshal([]byte(`{"stemcell": {"iaas": "vsphere"}}`), &params)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(params.Stemcell.Selected()).Should(BeTrue())
+			Expect(versionInfo.SelectStemcells(params.Stemcell)).Should(Equal([]types.Stemcell{vsphere, windows}))
+
+			err = json.Unmarshal([]byte(`{"stemcell": {"iaas": "vsphere", "os": "windows"}}`), &params)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.SelectStemcells(params.Stemcell)).Should(Equal([]types.Stemcell{windows}))
+		})
+	})
+
+	when("stemcell is an empty map", func() {
+		it("returns an error rather than downloading the product", func() {
+			err := json.Unmarshal([]byte(`{"stemcell": {}}`), &params)
+			Expect(err).Should(MatchError("stemcell must name at least one tag, or be true to select every stemcell"))
+		})
+	})
+
+	when("the product also has the stemcell_version shorthand", func() {
+		it("selects it along with the listed stemcells", func() {
+			versionInfo.StemcellVersion = "97.30"
+			versionInfo.StemcellFilePattern = "*vsphere*"
+			err := json.Unmarshal([]byte(`{"stemcell": true}`), &params)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versionInfo.SelectStemcells(params.Stemcell)).Should(Equal([]types.Stemcell{
+				{PivotalProduct: "stemcells", Version: "97.30", FilePattern: "*vsphere*"},
+				vsphere, aws, windows,
+			}))
+		})
+	})
+
+	when("stemcell is something else", func() {
+		it("returns an error", func() {
+			err := json.Unmarshal([]byte(`{"stemcell": "vsphere"}`), &params)
+			Expect(err).Should(MatchError(ContainSubstring("stemcell must be true, false or a map of tags")))
+		})
+	})
+}
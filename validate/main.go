@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pivotalservices/file-downloader-resource/config"
+)
+
+var VERSION = "0.0.0-dev"
+
+// vars - collects repeated -var name=value flags
+type vars map[string]string
+
+func (v vars) String() string {
+	return fmt.Sprint(map[string]string(v))
+}
+
+func (v vars) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("expected name=value, got %s", value)
+	}
+	v[parts[0]] = parts[1]
+	return nil
+}
+
+func main() {
+	variables := vars{}
+	flag.Var(variables, "var", "value for a ((variable)) in the product files, as name=value (repeatable)")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		println("version: " + VERSION)
+		println("usage: " + os.Args[0] + " [-var name=value]... <product file>...")
+		os.Exit(1)
+	}
+
+	os.Exit(validate(flag.Args(), variables, os.Stderr))
+}
+
+// validate - validates each product file in names, writing the outcome of each to out,
+// and returns the exit status, which is 1 when any of them is invalid
+func validate(names []string, variables map[string]string, out io.Writer) int {
+	status := 0
+	for _, name := range names {
+		// files starting with _, such as _defaults.yml, are only validated as part of the products that use them
+		productName := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+		if strings.HasPrefix(productName, "_") {
+			continue
+		}
+
+		provider := &config.DirProvider{
+			VersionRoot: filepath.Dir(name),
+			Vars:        variables,
+		}
+		_, err := provider.GetVersionInfo("", productName)
+		if err != nil {
+			fmt.Fprintln(out, err.Error())
+			status = 1
+			continue
+		}
+		fmt.Fprintln(out, name+": ok")
+	}
+
+	return status
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestValidate(t *testing.T) {
+	spec.Run(t, "validate", testValidate, spec.Report(report.Terminal{}))
+}
+
+func testValidate(t *testing.T, when spec.G, it spec.S) {
+	var (
+		versionRoot string
+		out         *bytes.Buffer
+		write       = func(name, contents string) string {
+			path := filepath.Join(versionRoot, name)
+			Expect(ioutil.WriteFile(path, []byte(contents), 0644)).Should(Succeed())
+			return path
+		}
+	)
+	it.Before(func() {
+		RegisterTestingT(t)
+		var err error
+		versionRoot, err = ioutil.TempDir("", "validate")
+		Expect(err).ShouldNot(HaveOccurred())
+		out = &bytes.Buffer{}
+	})
+	it.After(func() {
+		os.RemoveAll(versionRoot)
+	})
+
+	when("every product file is valid", func() {
+		it("reports each file as ok and exits 0", func() {
+			pas := write("pas.yml", "version: 2.1.5\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\n")
+			opsman := write("opsman.json", `{"version": "2.1.3", "product": "ops-manager", "file_pattern": "pcf-vsphere-*.ova"}`)
+
+			Expect(validate([]string{pas, opsman}, nil, out)).Should(Equal(0))
+			Expect(out.String()).Should(Equal(pas + ": ok\n" + opsman + ": ok\n"))
+		})
+	})
+
+	when("a product file is invalid", func() {
+		it("reports every problem with its line and exits 1", func() {
+			pas := write("pas.yml", "version: 2.1.5\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\n")
+			opsman := write("opsman.yml", "version: 2.1.3\nprodcut: ops-manager\nfile_pattern: pcf-vsphere-*.ova\n")
+
+			Expect(validate([]string{pas, opsman}, nil, out)).Should(Equal(1))
+			Expect(out.String()).Should(ContainSubstring(pas + ": ok\n"))
+			Expect(out.String()).Should(ContainSubstring(opsman + ":2: unknown field prodcut"))
+			Expect(out.String()).Should(ContainSubstring(opsman + ": product is required"))
+		})
+	})
+
+	when("product files reference variables", func() {
+		it("resolves them from -var flags", func() {
+			pas := write("pas.yml", "version: ((pas_version))\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\n")
+
+			Expect(validate([]string{pas}, map[string]string{"pas_version": "2.1.5"}, out)).Should(Equal(0))
+			Expect(validate([]string{pas}, nil, out)).Should(Equal(1))
+			Expect(out.String()).Should(ContainSubstring("unresolved variable ((pas_version))"))
+		})
+	})
+
+	when("a file starts with _", func() {
+		it("is only validated as part of the products that use it", func() {
+			defaults := write("_defaults.yml", "stemcell_version: \"97.28\"\n")
+
+			Expect(validate([]string{defaults}, nil, out)).Should(Equal(0))
+			Expect(out.String()).Should(BeEmpty())
+
+			pas := write("pas.yml", "version: 2.1.5\nproduct: elastic-runtime\nfile_pattern: cf-*.pivotal\n")
+			Expect(validate([]string{defaults, pas}, nil, out)).Should(Equal(1))
+			Expect(out.String()).Should(ContainSubstring("stemcell_file_pattern is required"))
+		})
+	})
+}